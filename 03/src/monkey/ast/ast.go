@@ -1,14 +1,21 @@
 package ast
 
 import (
-	"monkey/token"
 	"bytes"
+	"fmt"
+	"monkey/token"
 	"strings"
+	"unicode"
 )
 
+// every node knows the source range it was parsed from. Pos is the
+// position of the first character of the node, End the position right
+// after its last character.
 type Node interface {
 	TokenLiteral() string
 	String() string
+	Pos() token.Position
+	End() token.Position
 }
 
 type Statement interface {
@@ -34,33 +41,75 @@ func (p *Program) TokenLiteral() string {
 }
 
 func (p *Program) String() string {
+	return joinStatements(p.Statements)
+}
+
+// joinStatements renders a statement list so that it parses back into the
+// same statements, a ; is put between statements that do not end in one
+// so that the next statement cannot continue the expression before it
+func joinStatements(statements []Statement) string {
 	var out bytes.Buffer
-	for _,s := range p.Statements {
-		out.WriteString(s.String())
+	for i, s := range statements {
+		str := s.String()
+		out.WriteString(str)
+		if i+1 < len(statements) && !strings.HasSuffix(str, ";") {
+			out.WriteString(";")
+		}
 	}
 	return out.String()
 }
 
+// nodeString is the String of a child that is nil when it failed to
+// parse, the rest of the node is still written
+func nodeString(n Node) string {
+	if n == nil {
+		return ""
+	}
+	return n.String()
+}
+
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
+func (p *Program) End() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[len(p.Statements)-1].End()
+	}
+	return token.Position{}
+}
+
 // let statementNode
 type LetStatement struct {
 	Token token.Token
-	Name *Identifier
+	Name  *Identifier
 	Value Expression
 }
 
-func (ls *LetStatement) statementNode() {}
+func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.String())
 	out.WriteString(" = ")
-	if (ls != nil) {
-		out.WriteString(ls.Value.String())
-	}
+	out.WriteString(nodeString(ls.Value))
 	out.WriteString(";")
 	return out.String()
 }
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Pos }
+func (ls *LetStatement) End() token.Position {
+	if ls.Value != nil {
+		return ls.Value.End()
+	}
+	if ls.Name != nil {
+		return ls.Name.End()
+	}
+	return ls.Token.End
+}
 
 // identifer
 type Identifier struct {
@@ -68,50 +117,119 @@ type Identifier struct {
 	Value string
 }
 
-func (i *Identifier) expressionNode() {}
-func (i *Identifier) TokenLiteral() string { return i.Token.Literal}
+func (i *Identifier) expressionNode()      {}
+func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string {
+	if i == nil {
+		return ""
+	}
 	return i.Value
 }
-
+func (i *Identifier) Pos() token.Position { return i.Token.Pos }
+func (i *Identifier) End() token.Position { return i.Token.End }
 
 // integer literal
 type IntegerLiteral struct {
 	Value int64
 	Token token.Token
 }
-func (il *IntegerLiteral) expressionNode() {}
+
+func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Pos }
+func (il *IntegerLiteral) End() token.Position { return il.Token.End }
+
+// float literal
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) End() token.Position  { return fl.Token.End }
+
+// string literal, Value holds the string with the escape sequences
+// already resolved
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string       { return quote(sl.Value) }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
+func (sl *StringLiteral) End() token.Position  { return sl.Token.End }
+
+// quote turns s back into a monkey string literal
+func quote(s string) string {
+	var out bytes.Buffer
+
+	out.WriteByte('"')
+	for _, ch := range s {
+		switch ch {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\t':
+			out.WriteString(`\t`)
+		case '\r':
+			out.WriteString(`\r`)
+		default:
+			if unicode.IsPrint(ch) {
+				out.WriteRune(ch)
+			} else {
+				fmt.Fprintf(&out, "\\u{%x}", ch)
+			}
+		}
+	}
+	out.WriteByte('"')
+
+	return out.String()
+}
 
 // return statement
 type ReturnStatement struct {
-	Token token.Token
+	Token       token.Token
 	ReturnValue Expression
 }
 
-func (rs *ReturnStatement) statementNode() {}
-func (rs *ReturnStatement) TokenLiteral() string {return rs.Token.Literal}
+func (rs *ReturnStatement) statementNode()       {}
+func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(rs.TokenLiteral() + " ")
-	if (rs.ReturnValue != nil) {
+	if rs.ReturnValue != nil {
 		out.WriteString(rs.ReturnValue.String())
 	}
 	out.WriteString(";")
 	return out.String()
 }
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Pos }
+func (rs *ReturnStatement) End() token.Position {
+	if rs.ReturnValue != nil {
+		return rs.ReturnValue.End()
+	}
+	return rs.Token.End
+}
 
 // expression Statement
 type ExpressionStatement struct {
-	Token token.Token
+	Token      token.Token
 	Expression Expression
 }
 
-func (es *ExpressionStatement) statementNode() {}
-func (es *ExpressionStatement) TokenLiteral() string {return es.Token.Literal}
+func (es *ExpressionStatement) statementNode()       {}
+func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -119,42 +237,73 @@ func (es *ExpressionStatement) String() string {
 
 	return ""
 }
+func (es *ExpressionStatement) Pos() token.Position {
+	if es.Expression != nil {
+		return es.Expression.Pos()
+	}
+	return es.Token.Pos
+}
+func (es *ExpressionStatement) End() token.Position {
+	if es.Expression != nil {
+		return es.Expression.End()
+	}
+	return es.Token.End
+}
 
 // prefix expression
 type PrefixExpression struct {
-	Token token.Token
+	Token    token.Token
 	Operator string
-	Right Expression
+	Right    Expression
 }
 
-func (ps *PrefixExpression) expressionNode() {}
+func (ps *PrefixExpression) expressionNode()      {}
 func (ps *PrefixExpression) TokenLiteral() string { return ps.Token.Literal }
 func (ps *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
 	out.WriteString(ps.Operator)
-	out.WriteString(ps.Right.String())
+	out.WriteString(nodeString(ps.Right))
 	out.WriteString(")")
 	return out.String()
 }
+func (ps *PrefixExpression) Pos() token.Position { return ps.Token.Pos }
+func (ps *PrefixExpression) End() token.Position {
+	if ps.Right != nil {
+		return ps.Right.End()
+	}
+	return ps.Token.End
+}
 
 // infix expression
 type InfixExpression struct {
-	Token token.Token
-	Left Expression
+	Token    token.Token
+	Left     Expression
 	Operator string
-	Right Expression
+	Right    Expression
 }
 
-func (ie *InfixExpression) expressionNode() {}
+func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
-	out.WriteString(ie.Left.String()+" "+ie.Operator+" "+ie.Right.String())
+	out.WriteString(nodeString(ie.Left) + " " + ie.Operator + " " + nodeString(ie.Right))
 	out.WriteString(")")
 	return out.String()
 }
+func (ie *InfixExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+func (ie *InfixExpression) End() token.Position {
+	if ie.Right != nil {
+		return ie.Right.End()
+	}
+	return ie.Token.End
+}
 
 // boolean
 type Boolean struct {
@@ -162,100 +311,367 @@ type Boolean struct {
 	Value bool
 }
 
-func (b *Boolean) expressionNode() {}
+func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
-func (b *Boolean) String() string { return b.Token.Literal }
+func (b *Boolean) String() string       { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
+func (b *Boolean) End() token.Position  { return b.Token.End }
 
-// if expression
+// if expression, else if branches are kept in a flat list instead of
+// nesting an if expression inside every Alternative
 type IfExpression struct {
-	Token token.Token
-	Condition Expression
+	Token       token.Token
+	Condition   Expression
 	Consequence *BlockStatement
+	ElseIfs     []*ElseIf
 	Alternative *BlockStatement
 }
 
-func (i *IfExpression) expressionNode() {}
+// else if branch of an if expression, Token is the else
+type ElseIf struct {
+	Token       token.Token
+	Condition   Expression
+	Consequence *BlockStatement
+}
+
+func (i *IfExpression) expressionNode()      {}
 func (i *IfExpression) TokenLiteral() string { return i.Token.Literal }
 func (i *IfExpression) String() string {
 	var out bytes.Buffer
 
-	out.WriteString("if")
-	out.WriteString(i.Condition.String())
-	out.WriteString(" ")
+	out.WriteString("if (")
+	out.WriteString(nodeString(i.Condition))
+	out.WriteString(") ")
 	out.WriteString(i.Consequence.String())
+	for _, ei := range i.ElseIfs {
+		out.WriteString(" else if (")
+		out.WriteString(nodeString(ei.Condition))
+		out.WriteString(") ")
+		out.WriteString(ei.Consequence.String())
+	}
 	if i.Alternative != nil {
-		out.WriteString("else ")
+		out.WriteString(" else ")
 		out.WriteString(i.Alternative.String())
 	}
 
 	return out.String()
 }
+func (i *IfExpression) Pos() token.Position { return i.Token.Pos }
+func (i *IfExpression) End() token.Position {
+	if i.Alternative != nil {
+		return i.Alternative.End()
+	}
+	if len(i.ElseIfs) > 0 && i.ElseIfs[len(i.ElseIfs)-1].Consequence != nil {
+		return i.ElseIfs[len(i.ElseIfs)-1].Consequence.End()
+	}
+	if i.Consequence != nil {
+		return i.Consequence.End()
+	}
+	return i.Token.End
+}
 
 // BlockStatement
 type BlockStatement struct {
-	Token token.Token
+	Token      token.Token
 	Statements []Statement
+	Rbrace     token.Token
 }
 
-func (bs *BlockStatement) statementNode() {}
+func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
 func (bs *BlockStatement) String() string {
-	var out bytes.Buffer
-
-	for _,st := range bs.Statements {
-		out.WriteString(st.String())
+	if bs == nil {
+		return ""
 	}
-
-	return out.String()
+	if len(bs.Statements) == 0 {
+		return "{}"
+	}
+	return "{ " + joinStatements(bs.Statements) + " }"
 }
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Pos }
+func (bs *BlockStatement) End() token.Position { return bs.Rbrace.End }
 
 // Function Literal
 type FunctionLiteral struct {
-	Token token.Token
+	Token      token.Token
 	Parameters []*Identifier
-	Body *BlockStatement
+	Body       *BlockStatement
 }
 
-func (fl *FunctionLiteral) expressionNode() {}
+func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	params := []string{}
-	for _,p := range fl.Parameters {
+	for _, p := range fl.Parameters {
 		params = append(params, p.String())
 	}
 
-	out.WriteString(fl.TokenLiteral())
-	out.WriteString("(")
-	out.WriteString(strings.Join(params,","))
-	out.WriteString(")")
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Pos }
+func (fl *FunctionLiteral) End() token.Position {
+	if fl.Body != nil {
+		return fl.Body.End()
+	}
+	return fl.Token.End
+}
 
 // Call Expression
 type CallExpression struct {
-	Token token.Token
-	Function Expression
+	Token     token.Token
+	Function  Expression
 	Arguments []Expression
+	Rparen    token.Token
 }
 
-func (cl *CallExpression) expressionNode() {}
+func (cl *CallExpression) expressionNode()      {}
 func (cl *CallExpression) TokenLiteral() string { return cl.Token.Literal }
 func (cl *CallExpression) String() string {
 	var out bytes.Buffer
 
 	args := []string{}
-	for _,a := range  cl.Arguments {
-		args = append(args, a.String())
+	for _, a := range cl.Arguments {
+		args = append(args, nodeString(a))
 	}
 
-	out.WriteString(cl.Function.String())
+	out.WriteString(nodeString(cl.Function))
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
 
 	return out.String()
-}
\ No newline at end of file
+}
+func (cl *CallExpression) Pos() token.Position {
+	if cl.Function != nil {
+		return cl.Function.Pos()
+	}
+	return cl.Token.Pos
+}
+func (cl *CallExpression) End() token.Position {
+	if cl.Rparen.End.IsValid() {
+		return cl.Rparen.End
+	}
+	return cl.Token.End
+}
+
+// Array Literal
+type ArrayLiteral struct {
+	Token    token.Token
+	Elements []Expression
+	Rbracket token.Token
+}
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, nodeString(el))
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Pos }
+func (al *ArrayLiteral) End() token.Position {
+	if al.Rbracket.End.IsValid() {
+		return al.Rbracket.End
+	}
+	return al.Token.End
+}
+
+// Index Expression
+type IndexExpression struct {
+	Token    token.Token
+	Left     Expression
+	Index    Expression
+	Rbracket token.Token
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(nodeString(ie.Left))
+	out.WriteString("[")
+	out.WriteString(nodeString(ie.Index))
+	out.WriteString("])")
+
+	return out.String()
+}
+func (ie *IndexExpression) Pos() token.Position {
+	if ie.Left != nil {
+		return ie.Left.Pos()
+	}
+	return ie.Token.Pos
+}
+func (ie *IndexExpression) End() token.Position {
+	if ie.Rbracket.End.IsValid() {
+		return ie.Rbracket.End
+	}
+	if ie.Index != nil {
+		return ie.Index.End()
+	}
+	return ie.Token.End
+}
+
+// Hash Literal, the pairs are kept in source order
+type HashLiteral struct {
+	Token  token.Token
+	Pairs  []HashPair
+	Rbrace token.Token
+}
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, nodeString(pair.Key)+": "+nodeString(pair.Value))
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Pos }
+func (hl *HashLiteral) End() token.Position {
+	if hl.Rbrace.End.IsValid() {
+		return hl.Rbrace.End
+	}
+	return hl.Token.End
+}
+
+// while statement
+type WhileStatement struct {
+	Token     token.Token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while (")
+	out.WriteString(nodeString(ws.Condition))
+	out.WriteString(") ")
+	out.WriteString(ws.Body.String())
+
+	return out.String()
+}
+func (ws *WhileStatement) Pos() token.Position { return ws.Token.Pos }
+func (ws *WhileStatement) End() token.Position {
+	if ws.Body != nil {
+		return ws.Body.End()
+	}
+	return ws.Token.End
+}
+
+// for in statement, Variable is bound to every element of Iterable in
+// turn
+type ForStatement struct {
+	Token    token.Token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for ")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(nodeString(fs.Iterable))
+	out.WriteString(" ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+func (fs *ForStatement) Pos() token.Position { return fs.Token.Pos }
+func (fs *ForStatement) End() token.Position {
+	if fs.Body != nil {
+		return fs.Body.End()
+	}
+	return fs.Token.End
+}
+
+// break statement
+type BreakStatement struct {
+	Token token.Token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) String() string       { return bs.Token.Literal + ";" }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) End() token.Position  { return bs.Token.End }
+
+// continue statement
+type ContinueStatement struct {
+	Token token.Token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) String() string       { return cs.Token.Literal + ";" }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) End() token.Position  { return cs.Token.End }
+
+// assign expression, Operator is = or one of the compound operators like
+// +=. Target is either an identifier or an index expression.
+type AssignExpression struct {
+	Token    token.Token
+	Target   Expression
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(nodeString(ae.Target) + " " + ae.Operator + " " + nodeString(ae.Value))
+	out.WriteString(")")
+	return out.String()
+}
+func (ae *AssignExpression) Pos() token.Position {
+	if ae.Target != nil {
+		return ae.Target.Pos()
+	}
+	return ae.Token.Pos
+}
+func (ae *AssignExpression) End() token.Position {
+	if ae.Value != nil {
+		return ae.Value.End()
+	}
+	return ae.Token.End
+}
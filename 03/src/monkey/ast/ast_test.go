@@ -6,7 +6,7 @@ import (
 )
 
 func TestString(t *testing.T) {
-	program := &Program {
+	program := &Program{
 		Statements: []Statement{
 			&LetStatement{
 				Token: token.Token{Type: token.LET, Literal: "let"},
@@ -16,13 +16,40 @@ func TestString(t *testing.T) {
 				},
 				Value: &Identifier{
 					Token: token.Token{Type: token.IDENT, Literal: "anotherVar"},
-					Value: "anotherVar",	
+					Value: "anotherVar",
 				},
 			},
 		},
 	}
 
 	if program.String() != "let myVar = anotherVar;" {
-		t.Errorf(" program.String() wrong  got= %q ",program.String())
+		t.Errorf(" program.String() wrong  got= %q ", program.String())
 	}
-}
\ No newline at end of file
+}
+
+func TestStringWithMissingChildren(t *testing.T) {
+	// the parser leaves children nil when they fail to parse
+	tests := []struct {
+		node     Node
+		expected string
+	}{
+		{&LetStatement{Token: token.Token{Literal: "let"}, Name: ident("x")}, "let x = ;"},
+		{&PrefixExpression{Operator: "-"}, "(-)"},
+		{&InfixExpression{Left: integer(1), Operator: "+"}, "(1 + )"},
+		{&AssignExpression{Target: ident("x"), Operator: "="}, "(x = )"},
+		{&CallExpression{Arguments: []Expression{nil}}, "()"},
+		{&ArrayLiteral{Elements: []Expression{integer(1), nil}}, "[1, ]"},
+		{&IndexExpression{Left: ident("a")}, "(a[])"},
+		{&HashLiteral{Pairs: []HashPair{{Key: integer(1)}}}, "{1: }"},
+		{&IfExpression{ElseIfs: []*ElseIf{{}}}, "if ()  else if () "},
+		{&FunctionLiteral{Parameters: []*Identifier{ident("a")}}, "fn(a) "},
+		{&WhileStatement{}, "while () "},
+		{&ForStatement{}, "for  in  "},
+	}
+
+	for _, tt := range tests {
+		if tt.node.String() != tt.expected {
+			t.Errorf(" %T rendered as %q expected %q ", tt.node, tt.node.String(), tt.expected)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// detail is the part of a node that is not a child, like the operator of
+// an infix expression or the value of a literal
+func detail(n Node) string {
+	switch n := n.(type) {
+	case *Identifier:
+		return n.Value
+	case *IntegerLiteral:
+		if n.Token.Literal != "" {
+			return n.Token.Literal
+		}
+		return fmt.Sprint(n.Value)
+	case *FloatLiteral:
+		if n.Token.Literal != "" {
+			return n.Token.Literal
+		}
+		return fmt.Sprint(n.Value)
+	case *StringLiteral:
+		return quote(n.Value)
+	case *Boolean:
+		return fmt.Sprint(n.Value)
+	case *PrefixExpression:
+		return n.Operator
+	case *InfixExpression:
+		return n.Operator
+	case *AssignExpression:
+		return n.Operator
+	default:
+		return ""
+	}
+}
+
+func kindOf(n Node) string {
+	return reflect.TypeOf(n).Elem().Name()
+}
+
+// DOT renders the tree rooted at node as a Graphviz digraph. Every node
+// is a box labelled with its type and operator or value, the edges are
+// labelled with the field the child is stored in. Missing children are
+// left out.
+func DOT(node Node) string {
+	var out bytes.Buffer
+	out.WriteString("digraph ast {\n")
+	out.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+
+	next := 0
+	var write func(n Node) int
+	write = func(n Node) int {
+		id := next
+		next += 1
+
+		label := kindOf(n)
+		if d := detail(n); d != "" {
+			label += "\n" + d
+		}
+		fmt.Fprintf(&out, "\tn%d [label=%s];\n", id, dotQuote(label))
+
+		for _, f := range fieldsOf(n) {
+			if f.node == nil {
+				continue
+			}
+			child := write(f.node)
+			edge := dotQuote(f.name)
+			fmt.Fprintf(&out, "\tn%d -> n%d [label=%s];\n", id, child, edge)
+		}
+		return id
+	}
+	if node != nil {
+		write(node)
+	}
+
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// dotQuote writes s as a DOT string, a newline becomes a line break
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// sexpr is an atom or, when list is not nil, a parenthesised list
+type sexpr struct {
+	atom string
+	list []sexpr
+	// program puts every item on a line of its own
+	program bool
+}
+
+func atom(s string) sexpr {
+	return sexpr{atom: s}
+}
+
+func list(items ...sexpr) sexpr {
+	return sexpr{list: append([]sexpr{}, items...)}
+}
+
+// SExpr renders the tree rooted at node as an indented S-expression, for
+// example (+ a (* b c)) for a + b * c. Operators and keywords come first in
+// their list, literals and identifiers are written as atoms and missing
+// children as _. Expression statements are left out since they only wrap
+// their expression.
+func SExpr(node Node) string {
+	var out bytes.Buffer
+	writeSExpr(&out, toSExpr(node), 0)
+	out.WriteString("\n")
+	return out.String()
+}
+
+func toSExpr(n Node) sexpr {
+	if n == nil || reflect.ValueOf(n).IsNil() {
+		return atom("_")
+	}
+
+	children := func(head ...sexpr) sexpr {
+		for _, f := range fieldsOf(n) {
+			head = append(head, toSExpr(f.node))
+		}
+		return list(head...)
+	}
+
+	switch n := n.(type) {
+	case *Program:
+		s := children(atom("program"))
+		s.program = true
+		return s
+	case *LetStatement:
+		return list(atom("let"), toSExpr(n.Name), toSExpr(n.Value))
+	case *ReturnStatement:
+		return children(atom("return"))
+	case *ExpressionStatement:
+		return toSExpr(n.Expression)
+	case *BlockStatement:
+		return children(atom("block"))
+	case *WhileStatement:
+		return children(atom("while"))
+	case *ForStatement:
+		return children(atom("for"))
+	case *BreakStatement:
+		return list(atom("break"))
+	case *ContinueStatement:
+		return list(atom("continue"))
+	case *PrefixExpression, *InfixExpression, *AssignExpression:
+		return children(atom(detail(n)))
+	case *IfExpression:
+		s := list(atom("if"), toSExpr(n.Condition), toSExpr(n.Consequence))
+		for _, ei := range n.ElseIfs {
+			branch := list(atom("else-if"), toSExpr(ei.Condition))
+			branch.list = append(branch.list, toSExpr(ei.Consequence))
+			s.list = append(s.list, branch)
+		}
+		if n.Alternative != nil {
+			s.list = append(s.list, list(atom("else"), toSExpr(n.Alternative)))
+		}
+		return s
+	case *FunctionLiteral:
+		params := list()
+		for _, p := range n.Parameters {
+			params.list = append(params.list, toSExpr(p))
+		}
+		return list(atom("fn"), params, toSExpr(n.Body))
+	case *CallExpression:
+		return children(atom("call"))
+	case *ArrayLiteral:
+		return children(atom("array"))
+	case *IndexExpression:
+		return children(atom("index"))
+	case *HashLiteral:
+		s := list(atom("hash"))
+		for _, pair := range n.Pairs {
+			entry := list(atom("pair"), toSExpr(pair.Key), toSExpr(pair.Value))
+			s.list = append(s.list, entry)
+		}
+		return s
+	default:
+		return atom(detail(n))
+	}
+}
+
+// writeSExpr writes a list on one line when it fits into lineWidth,
+// except for the program which gets a line per statement. Otherwise the
+// atoms it starts with stay on the first line and every other item goes on
+// a line of its own, indented by two spaces.
+func writeSExpr(out *bytes.Buffer, s sexpr, indent int) {
+	if s.list == nil {
+		out.WriteString(s.atom)
+		return
+	}
+
+	flat := s.flat()
+	if !s.program && len(flat) <= lineWidth {
+		out.WriteString(flat)
+		return
+	}
+
+	out.WriteString("(")
+	head := true
+	for i, item := range s.list {
+		if head && item.list == nil {
+			if i > 0 {
+				out.WriteString(" ")
+			}
+			out.WriteString(item.atom)
+			continue
+		}
+		head = false
+		out.WriteString("\n")
+		out.WriteString(strings.Repeat("  ", indent+1))
+		writeSExpr(out, item, indent+1)
+	}
+	out.WriteString(")")
+}
+
+const lineWidth = 60
+
+func (s sexpr) flat() string {
+	if s.list == nil {
+		return s.atom
+	}
+	items := []string{}
+	for _, item := range s.list {
+		items = append(items, item.flat())
+	}
+	return "(" + strings.Join(items, " ") + ")"
+}
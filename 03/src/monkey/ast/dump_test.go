@@ -0,0 +1,69 @@
+package ast
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSExpr(t *testing.T) {
+	// a + b * c
+	precedence := &Program{Statements: []Statement{
+		exprStmt(&InfixExpression{
+			Left:     ident("a"),
+			Operator: "+",
+			Right:    &InfixExpression{Left: ident("b"), Operator: "*", Right: ident("c")},
+		}),
+	}}
+
+	tests := []struct {
+		input    Node
+		expected string
+	}{
+		{precedence, "(program\n  (+ a (* b c)))\n"},
+		{&InfixExpression{Left: nil, Operator: "-", Right: integer(1)}, "(- _ 1)\n"},
+		{&FunctionLiteral{Parameters: []*Identifier{}, Body: block()}, "(fn () (block))\n"},
+		{everyNode(), strings.Join([]string{
+			"(program",
+			"  (let a (fn (b c) (block (return (+ d 1)))))",
+			"  (if",
+			"    (! true)",
+			"    (block 2)",
+			"    (else-if e (block 3))",
+			"    (else (block 1.5)))",
+			"  (while f (block (break)))",
+			"  (for g (array 4) (block (continue)))",
+			"  (call h \"s\" 5)",
+			"  (= (index i 6) (hash (pair j 7))))",
+		}, "\n") + "\n"},
+	}
+
+	for _, tt := range tests {
+		if got := SExpr(tt.input); got != tt.expected {
+			t.Errorf(" wrong s-expression\n got\n%s expected\n%s ", got, tt.expected)
+		}
+	}
+}
+
+func TestDOT(t *testing.T) {
+	program := &Program{Statements: []Statement{
+		exprStmt(&PrefixExpression{Operator: "-", Right: &StringLiteral{Value: `a"b`}}),
+		&ReturnStatement{},
+	}}
+
+	expected := `digraph ast {
+	node [shape=box, fontname="monospace"];
+	n0 [label="Program"];
+	n1 [label="ExpressionStatement"];
+	n2 [label="PrefixExpression\n-"];
+	n3 [label="StringLiteral\n\"a\\\"b\""];
+	n2 -> n3 [label="right"];
+	n1 -> n2 [label="expression"];
+	n0 -> n1 [label="statements[0]"];
+	n4 [label="ReturnStatement"];
+	n0 -> n4 [label="statements[1]"];
+}
+`
+	if got := DOT(program); got != expected {
+		t.Errorf(" wrong dot\n got\n%s expected\n%s ", got, expected)
+	}
+}
@@ -0,0 +1,1004 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"monkey/token"
+	"unicode/utf8"
+)
+
+// Every node marshals to a JSON object with a "kind" naming its type, a
+// "span" with the start and end position it was parsed from and one field
+// for each of its children. Missing children are null, lists are never
+// null. The same objects unmarshal back into nodes whose String and spans
+// match the original. Only complete trees unmarshal, a null child other
+// than the alternative of an if is an error.
+
+type jsonPosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonSpan struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+func spanOf(n Node) *jsonSpan {
+	pos, end := n.Pos(), n.End()
+	if !pos.IsValid() {
+		return nil
+	}
+	return &jsonSpan{
+		Start: jsonPosition{Offset: pos.Offset, Line: pos.Line, Column: pos.Column},
+		End:   jsonPosition{Offset: end.Offset, Line: end.Line, Column: end.Column},
+	}
+}
+
+func (p jsonPosition) position() token.Position {
+	return token.Position{Offset: p.Offset, Line: p.Line, Column: p.Column}
+}
+
+// marshalNode writes the kind and span of n followed by the fields of
+// the struct children
+func marshalNode(kind string, n Node, children interface{}) ([]byte, error) {
+	head, err := json.Marshal(struct {
+		Kind string    `json:"kind"`
+		Span *jsonSpan `json:"span,omitempty"`
+	}{kind, spanOf(n)})
+	if err != nil {
+		return nil, err
+	}
+	if children == nil {
+		return head, nil
+	}
+
+	body, err := json.Marshal(children)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 2 {
+		return head, nil
+	}
+
+	out := append(head[:len(head)-1], ',')
+	return append(out, body[1:]...), nil
+}
+
+func statementList(list []Statement) []Statement {
+	if list == nil {
+		return []Statement{}
+	}
+	return list
+}
+
+func expressionList(list []Expression) []Expression {
+	if list == nil {
+		return []Expression{}
+	}
+	return list
+}
+
+func (p *Program) MarshalJSON() ([]byte, error) {
+	return marshalNode("Program", p, struct {
+		Statements []Statement `json:"statements"`
+	}{statementList(p.Statements)})
+}
+
+func (ls *LetStatement) MarshalJSON() ([]byte, error) {
+	return marshalNode("LetStatement", ls, struct {
+		Name  *Identifier `json:"name"`
+		Value Expression  `json:"value"`
+	}{ls.Name, ls.Value})
+}
+
+func (rs *ReturnStatement) MarshalJSON() ([]byte, error) {
+	return marshalNode("ReturnStatement", rs, struct {
+		ReturnValue Expression `json:"returnValue"`
+	}{rs.ReturnValue})
+}
+
+func (es *ExpressionStatement) MarshalJSON() ([]byte, error) {
+	return marshalNode("ExpressionStatement", es, struct {
+		Expression Expression `json:"expression"`
+	}{es.Expression})
+}
+
+func (bs *BlockStatement) MarshalJSON() ([]byte, error) {
+	return marshalNode("BlockStatement", bs, struct {
+		Statements []Statement `json:"statements"`
+	}{statementList(bs.Statements)})
+}
+
+func (ws *WhileStatement) MarshalJSON() ([]byte, error) {
+	return marshalNode("WhileStatement", ws, struct {
+		Condition Expression      `json:"condition"`
+		Body      *BlockStatement `json:"body"`
+	}{ws.Condition, ws.Body})
+}
+
+func (fs *ForStatement) MarshalJSON() ([]byte, error) {
+	return marshalNode("ForStatement", fs, struct {
+		Variable *Identifier     `json:"variable"`
+		Iterable Expression      `json:"iterable"`
+		Body     *BlockStatement `json:"body"`
+	}{fs.Variable, fs.Iterable, fs.Body})
+}
+
+func (bs *BreakStatement) MarshalJSON() ([]byte, error) {
+	return marshalNode("BreakStatement", bs, nil)
+}
+
+func (cs *ContinueStatement) MarshalJSON() ([]byte, error) {
+	return marshalNode("ContinueStatement", cs, nil)
+}
+
+func (i *Identifier) MarshalJSON() ([]byte, error) {
+	return marshalNode("Identifier", i, struct {
+		Value string `json:"value"`
+	}{i.Value})
+}
+
+// the literal keeps the spelling of the number, 0xff stays 0xff and
+// integers too large for a float64 are not rounded
+func (il *IntegerLiteral) MarshalJSON() ([]byte, error) {
+	return marshalNode("IntegerLiteral", il, struct {
+		Value   int64  `json:"value"`
+		Literal string `json:"literal"`
+	}{il.Value, il.Token.Literal})
+}
+
+func (fl *FloatLiteral) MarshalJSON() ([]byte, error) {
+	return marshalNode("FloatLiteral", fl, struct {
+		Value   float64 `json:"value"`
+		Literal string  `json:"literal"`
+	}{fl.Value, fl.Token.Literal})
+}
+
+func (sl *StringLiteral) MarshalJSON() ([]byte, error) {
+	return marshalNode("StringLiteral", sl, struct {
+		Value string `json:"value"`
+	}{sl.Value})
+}
+
+func (b *Boolean) MarshalJSON() ([]byte, error) {
+	return marshalNode("Boolean", b, struct {
+		Value bool `json:"value"`
+	}{b.Value})
+}
+
+func (ps *PrefixExpression) MarshalJSON() ([]byte, error) {
+	return marshalNode("PrefixExpression", ps, struct {
+		Operator string     `json:"operator"`
+		Right    Expression `json:"right"`
+	}{ps.Operator, ps.Right})
+}
+
+func (ie *InfixExpression) MarshalJSON() ([]byte, error) {
+	return marshalNode("InfixExpression", ie, struct {
+		Left     Expression `json:"left"`
+		Operator string     `json:"operator"`
+		Right    Expression `json:"right"`
+	}{ie.Left, ie.Operator, ie.Right})
+}
+
+func (ae *AssignExpression) MarshalJSON() ([]byte, error) {
+	return marshalNode("AssignExpression", ae, struct {
+		Target   Expression `json:"target"`
+		Operator string     `json:"operator"`
+		Value    Expression `json:"value"`
+	}{ae.Target, ae.Operator, ae.Value})
+}
+
+type jsonElseIf struct {
+	Span        *jsonSpan       `json:"span,omitempty"`
+	Condition   Expression      `json:"condition"`
+	Consequence *BlockStatement `json:"consequence"`
+}
+
+func (i *IfExpression) MarshalJSON() ([]byte, error) {
+	elseIfs := []jsonElseIf{}
+	for _, ei := range i.ElseIfs {
+		var span *jsonSpan
+		if ei.Token.Pos.IsValid() && ei.Consequence != nil && ei.Consequence.Pos().IsValid() {
+			span = &jsonSpan{
+				Start: jsonPosition{Offset: ei.Token.Pos.Offset, Line: ei.Token.Pos.Line, Column: ei.Token.Pos.Column},
+				End:   spanOf(ei.Consequence).End,
+			}
+		}
+		elseIfs = append(elseIfs, jsonElseIf{span, ei.Condition, ei.Consequence})
+	}
+
+	return marshalNode("IfExpression", i, struct {
+		Condition   Expression      `json:"condition"`
+		Consequence *BlockStatement `json:"consequence"`
+		ElseIfs     []jsonElseIf    `json:"elseIfs"`
+		Alternative *BlockStatement `json:"alternative"`
+	}{i.Condition, i.Consequence, elseIfs, i.Alternative})
+}
+
+func (fl *FunctionLiteral) MarshalJSON() ([]byte, error) {
+	parameters := fl.Parameters
+	if parameters == nil {
+		parameters = []*Identifier{}
+	}
+	return marshalNode("FunctionLiteral", fl, struct {
+		Parameters []*Identifier   `json:"parameters"`
+		Body       *BlockStatement `json:"body"`
+	}{parameters, fl.Body})
+}
+
+func (cl *CallExpression) MarshalJSON() ([]byte, error) {
+	return marshalNode("CallExpression", cl, struct {
+		Function  Expression   `json:"function"`
+		Arguments []Expression `json:"arguments"`
+	}{cl.Function, expressionList(cl.Arguments)})
+}
+
+func (al *ArrayLiteral) MarshalJSON() ([]byte, error) {
+	return marshalNode("ArrayLiteral", al, struct {
+		Elements []Expression `json:"elements"`
+	}{expressionList(al.Elements)})
+}
+
+func (ie *IndexExpression) MarshalJSON() ([]byte, error) {
+	return marshalNode("IndexExpression", ie, struct {
+		Left  Expression `json:"left"`
+		Index Expression `json:"index"`
+	}{ie.Left, ie.Index})
+}
+
+type jsonHashPair struct {
+	Key   Expression `json:"key"`
+	Value Expression `json:"value"`
+}
+
+func (hl *HashLiteral) MarshalJSON() ([]byte, error) {
+	pairs := []jsonHashPair{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, jsonHashPair{pair.Key, pair.Value})
+	}
+	return marshalNode("HashLiteral", hl, struct {
+		Pairs []jsonHashPair `json:"pairs"`
+	}{pairs})
+}
+
+// UnmarshalNode decodes a node of any kind written by MarshalJSON. It
+// returns nil for a JSON null.
+func UnmarshalNode(data []byte) (Node, error) {
+	var head struct {
+		Kind *string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+	if head.Kind == nil {
+		if string(data) == "null" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ast: node without kind")
+	}
+
+	newNode, ok := nodeKinds[*head.Kind]
+	if !ok {
+		return nil, fmt.Errorf("ast: unknown node kind %q", *head.Kind)
+	}
+
+	node := newNode()
+	if err := json.Unmarshal(data, node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+var nodeKinds = map[string]func() Node{
+	"Program":             func() Node { return &Program{} },
+	"LetStatement":        func() Node { return &LetStatement{} },
+	"ReturnStatement":     func() Node { return &ReturnStatement{} },
+	"ExpressionStatement": func() Node { return &ExpressionStatement{} },
+	"BlockStatement":      func() Node { return &BlockStatement{} },
+	"WhileStatement":      func() Node { return &WhileStatement{} },
+	"ForStatement":        func() Node { return &ForStatement{} },
+	"BreakStatement":      func() Node { return &BreakStatement{} },
+	"ContinueStatement":   func() Node { return &ContinueStatement{} },
+	"Identifier":          func() Node { return &Identifier{} },
+	"IntegerLiteral":      func() Node { return &IntegerLiteral{} },
+	"FloatLiteral":        func() Node { return &FloatLiteral{} },
+	"StringLiteral":       func() Node { return &StringLiteral{} },
+	"Boolean":             func() Node { return &Boolean{} },
+	"PrefixExpression":    func() Node { return &PrefixExpression{} },
+	"InfixExpression":     func() Node { return &InfixExpression{} },
+	"AssignExpression":    func() Node { return &AssignExpression{} },
+	"IfExpression":        func() Node { return &IfExpression{} },
+	"FunctionLiteral":     func() Node { return &FunctionLiteral{} },
+	"CallExpression":      func() Node { return &CallExpression{} },
+	"ArrayLiteral":        func() Node { return &ArrayLiteral{} },
+	"IndexExpression":     func() Node { return &IndexExpression{} },
+	"HashLiteral":         func() Node { return &HashLiteral{} },
+}
+
+// unmarshalStatement decodes the child field of a parent node, it is an
+// error for the child to be null or missing
+func unmarshalStatement(data json.RawMessage, parent, field string) (Statement, error) {
+	node, err := unmarshalChild(data, parent, field)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := node.(Statement)
+	if !ok {
+		return nil, fmt.Errorf("ast: %T is not a statement", node)
+	}
+	return s, nil
+}
+
+func unmarshalStatements(list []json.RawMessage, parent, field string) ([]Statement, error) {
+	statements := []Statement{}
+	for i, data := range list {
+		s, err := unmarshalStatement(data, parent, fmt.Sprintf("%s[%d]", field, i))
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, s)
+	}
+	return statements, nil
+}
+
+// unmarshalExpression is like unmarshalStatement for expressions
+func unmarshalExpression(data json.RawMessage, parent, field string) (Expression, error) {
+	node, err := unmarshalChild(data, parent, field)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := node.(Expression)
+	if !ok {
+		return nil, fmt.Errorf("ast: %T is not an expression", node)
+	}
+	return e, nil
+}
+
+func unmarshalExpressions(list []json.RawMessage, parent, field string) ([]Expression, error) {
+	expressions := []Expression{}
+	for i, data := range list {
+		e, err := unmarshalExpression(data, parent, fmt.Sprintf("%s[%d]", field, i))
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, e)
+	}
+	return expressions, nil
+}
+
+func unmarshalChild(data json.RawMessage, parent, field string) (Node, error) {
+	if len(data) == 0 {
+		return nil, missing(parent, field)
+	}
+	node, err := UnmarshalNode(data)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, missing(parent, field)
+	}
+	return node, nil
+}
+
+// missing is the error for a child that is null or left out, the children
+// decoded straight into an *Identifier or *BlockStatement are checked with
+// it after decoding
+func missing(parent, field string) error {
+	return fmt.Errorf("ast: %s without %s", parent, field)
+}
+
+// jsonNode holds the fields every node has, the children are decoded by
+// the node itself
+type jsonNode struct {
+	Kind string    `json:"kind"`
+	Span *jsonSpan `json:"span"`
+}
+
+func (n jsonNode) check(kind string) error {
+	if n.Kind != kind {
+		return fmt.Errorf("ast: cannot unmarshal %q into %s", n.Kind, kind)
+	}
+	return nil
+}
+
+// startToken rebuilds the token a node starts with, its end is known from
+// the length of the literal
+func (n jsonNode) startToken(t token.TokenType, literal string) token.Token {
+	tok := token.Token{Type: t, Literal: literal}
+	if n.Span != nil {
+		tok.Pos = n.Span.Start.position()
+		tok.End = tok.Pos
+		tok.End.Offset += len(literal)
+		tok.End.Column += utf8.RuneCountInString(literal)
+	}
+	return tok
+}
+
+// wholeToken rebuilds the token of a node that is a single token
+func (n jsonNode) wholeToken(t token.TokenType, literal string) token.Token {
+	tok := token.Token{Type: t, Literal: literal}
+	if n.Span != nil {
+		tok.Pos = n.Span.Start.position()
+		tok.End = n.Span.End.position()
+	}
+	return tok
+}
+
+// endToken rebuilds the closing bracket a node ends with
+func (n jsonNode) endToken(t token.TokenType) token.Token {
+	tok := token.Token{Type: t, Literal: string(t)}
+	if n.Span != nil {
+		tok.End = n.Span.End.position()
+		tok.Pos = tok.End
+		tok.Pos.Offset -= 1
+		tok.Pos.Column -= 1
+	}
+	return tok
+}
+
+// operatorToken rebuilds the token of an operator in the middle of a
+// node, its position is not known
+func operatorToken(operator string) token.Token {
+	return token.Token{Type: token.TokenType(operator), Literal: operator}
+}
+
+func (p *Program) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Statements []json.RawMessage `json:"statements"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("Program"); err != nil {
+		return err
+	}
+
+	statements, err := unmarshalStatements(v.Statements, "Program", "statements")
+	if err != nil {
+		return err
+	}
+	*p = Program{Statements: statements}
+	return nil
+}
+
+func (ls *LetStatement) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Name  *Identifier     `json:"name"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("LetStatement"); err != nil {
+		return err
+	}
+	if v.Name == nil {
+		return missing("LetStatement", "name")
+	}
+
+	value, err := unmarshalExpression(v.Value, "LetStatement", "value")
+	if err != nil {
+		return err
+	}
+	*ls = LetStatement{Token: v.startToken(token.LET, "let"), Name: v.Name, Value: value}
+	return nil
+}
+
+func (rs *ReturnStatement) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		ReturnValue json.RawMessage `json:"returnValue"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("ReturnStatement"); err != nil {
+		return err
+	}
+
+	value, err := unmarshalExpression(v.ReturnValue, "ReturnStatement", "returnValue")
+	if err != nil {
+		return err
+	}
+	*rs = ReturnStatement{Token: v.startToken(token.RETURN, "return"), ReturnValue: value}
+	return nil
+}
+
+func (es *ExpressionStatement) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Expression json.RawMessage `json:"expression"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("ExpressionStatement"); err != nil {
+		return err
+	}
+
+	expression, err := unmarshalExpression(v.Expression, "ExpressionStatement", "expression")
+	if err != nil {
+		return err
+	}
+	*es = ExpressionStatement{Token: leadingToken(expression), Expression: expression}
+	return nil
+}
+
+// leadingToken is the token an expression starts with, which the parser
+// stores in the expression statement around it. A ( in front of the
+// expression is not in the JSON, the token after it is used instead.
+func leadingToken(e Expression) token.Token {
+	switch e := e.(type) {
+	case *InfixExpression:
+		return leadingToken(e.Left)
+	case *AssignExpression:
+		return leadingToken(e.Target)
+	case *CallExpression:
+		return leadingToken(e.Function)
+	case *IndexExpression:
+		return leadingToken(e.Left)
+	case *Identifier:
+		return e.Token
+	case *IntegerLiteral:
+		return e.Token
+	case *FloatLiteral:
+		return e.Token
+	case *StringLiteral:
+		return e.Token
+	case *Boolean:
+		return e.Token
+	case *PrefixExpression:
+		return e.Token
+	case *IfExpression:
+		return e.Token
+	case *FunctionLiteral:
+		return e.Token
+	case *ArrayLiteral:
+		return e.Token
+	case *HashLiteral:
+		return e.Token
+	default:
+		return token.Token{}
+	}
+}
+
+func (bs *BlockStatement) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Statements []json.RawMessage `json:"statements"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("BlockStatement"); err != nil {
+		return err
+	}
+
+	statements, err := unmarshalStatements(v.Statements, "BlockStatement", "statements")
+	if err != nil {
+		return err
+	}
+	*bs = BlockStatement{Token: v.startToken(token.LBRACE, "{"), Statements: statements, Rbrace: v.endToken(token.RBRACE)}
+	return nil
+}
+
+func (ws *WhileStatement) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Condition json.RawMessage `json:"condition"`
+		Body      *BlockStatement `json:"body"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("WhileStatement"); err != nil {
+		return err
+	}
+	if v.Body == nil {
+		return missing("WhileStatement", "body")
+	}
+
+	condition, err := unmarshalExpression(v.Condition, "WhileStatement", "condition")
+	if err != nil {
+		return err
+	}
+	*ws = WhileStatement{Token: v.startToken(token.WHILE, "while"), Condition: condition, Body: v.Body}
+	return nil
+}
+
+func (fs *ForStatement) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Variable *Identifier     `json:"variable"`
+		Iterable json.RawMessage `json:"iterable"`
+		Body     *BlockStatement `json:"body"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("ForStatement"); err != nil {
+		return err
+	}
+	if v.Variable == nil {
+		return missing("ForStatement", "variable")
+	}
+	if v.Body == nil {
+		return missing("ForStatement", "body")
+	}
+
+	iterable, err := unmarshalExpression(v.Iterable, "ForStatement", "iterable")
+	if err != nil {
+		return err
+	}
+	*fs = ForStatement{Token: v.startToken(token.FOR, "for"), Variable: v.Variable, Iterable: iterable, Body: v.Body}
+	return nil
+}
+
+func (bs *BreakStatement) UnmarshalJSON(data []byte) error {
+	var v jsonNode
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("BreakStatement"); err != nil {
+		return err
+	}
+
+	*bs = BreakStatement{Token: v.startToken(token.BREAK, "break")}
+	return nil
+}
+
+func (cs *ContinueStatement) UnmarshalJSON(data []byte) error {
+	var v jsonNode
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("ContinueStatement"); err != nil {
+		return err
+	}
+
+	*cs = ContinueStatement{Token: v.startToken(token.CONTINUE, "continue")}
+	return nil
+}
+
+func (i *Identifier) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("Identifier"); err != nil {
+		return err
+	}
+
+	*i = Identifier{Token: v.wholeToken(token.IDENT, v.Value), Value: v.Value}
+	return nil
+}
+
+func (il *IntegerLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Value   int64  `json:"value"`
+		Literal string `json:"literal"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("IntegerLiteral"); err != nil {
+		return err
+	}
+
+	*il = IntegerLiteral{Token: v.wholeToken(token.INT, v.Literal), Value: v.Value}
+	return nil
+}
+
+func (fl *FloatLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Value   float64 `json:"value"`
+		Literal string  `json:"literal"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("FloatLiteral"); err != nil {
+		return err
+	}
+
+	*fl = FloatLiteral{Token: v.wholeToken(token.FLOAT, v.Literal), Value: v.Value}
+	return nil
+}
+
+func (sl *StringLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("StringLiteral"); err != nil {
+		return err
+	}
+
+	*sl = StringLiteral{Token: v.wholeToken(token.STRING, v.Value), Value: v.Value}
+	return nil
+}
+
+func (b *Boolean) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Value bool `json:"value"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("Boolean"); err != nil {
+		return err
+	}
+
+	tok := v.wholeToken(token.FALSE, "false")
+	if v.Value {
+		tok = v.wholeToken(token.TRUE, "true")
+	}
+	*b = Boolean{Token: tok, Value: v.Value}
+	return nil
+}
+
+func (ps *PrefixExpression) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Operator string          `json:"operator"`
+		Right    json.RawMessage `json:"right"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("PrefixExpression"); err != nil {
+		return err
+	}
+
+	right, err := unmarshalExpression(v.Right, "PrefixExpression", "right")
+	if err != nil {
+		return err
+	}
+	*ps = PrefixExpression{Token: v.startToken(token.TokenType(v.Operator), v.Operator), Operator: v.Operator, Right: right}
+	return nil
+}
+
+func (ie *InfixExpression) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Left     json.RawMessage `json:"left"`
+		Operator string          `json:"operator"`
+		Right    json.RawMessage `json:"right"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("InfixExpression"); err != nil {
+		return err
+	}
+
+	left, err := unmarshalExpression(v.Left, "InfixExpression", "left")
+	if err != nil {
+		return err
+	}
+	right, err := unmarshalExpression(v.Right, "InfixExpression", "right")
+	if err != nil {
+		return err
+	}
+	*ie = InfixExpression{Token: operatorToken(v.Operator), Left: left, Operator: v.Operator, Right: right}
+	return nil
+}
+
+func (ae *AssignExpression) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Target   json.RawMessage `json:"target"`
+		Operator string          `json:"operator"`
+		Value    json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("AssignExpression"); err != nil {
+		return err
+	}
+
+	target, err := unmarshalExpression(v.Target, "AssignExpression", "target")
+	if err != nil {
+		return err
+	}
+	value, err := unmarshalExpression(v.Value, "AssignExpression", "value")
+	if err != nil {
+		return err
+	}
+	*ae = AssignExpression{Token: operatorToken(v.Operator), Target: target, Operator: v.Operator, Value: value}
+	return nil
+}
+
+func (i *IfExpression) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Condition   json.RawMessage `json:"condition"`
+		Consequence *BlockStatement `json:"consequence"`
+		ElseIfs     []struct {
+			Span        *jsonSpan       `json:"span"`
+			Condition   json.RawMessage `json:"condition"`
+			Consequence *BlockStatement `json:"consequence"`
+		} `json:"elseIfs"`
+		Alternative *BlockStatement `json:"alternative"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("IfExpression"); err != nil {
+		return err
+	}
+	if v.Consequence == nil {
+		return missing("IfExpression", "consequence")
+	}
+
+	condition, err := unmarshalExpression(v.Condition, "IfExpression", "condition")
+	if err != nil {
+		return err
+	}
+
+	var elseIfs []*ElseIf
+	for n, ei := range v.ElseIfs {
+		condition, err := unmarshalExpression(ei.Condition, "IfExpression", fmt.Sprintf("elseIfs[%d].condition", n))
+		if err != nil {
+			return err
+		}
+		if ei.Consequence == nil {
+			return missing("IfExpression", fmt.Sprintf("elseIfs[%d].consequence", n))
+		}
+		branch := jsonNode{Span: ei.Span}
+		elseIfs = append(elseIfs, &ElseIf{Token: branch.startToken(token.ELSE, "else"), Condition: condition, Consequence: ei.Consequence})
+	}
+
+	*i = IfExpression{
+		Token:       v.startToken(token.IF, "if"),
+		Condition:   condition,
+		Consequence: v.Consequence,
+		ElseIfs:     elseIfs,
+		Alternative: v.Alternative,
+	}
+	return nil
+}
+
+func (fl *FunctionLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Parameters []*Identifier   `json:"parameters"`
+		Body       *BlockStatement `json:"body"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("FunctionLiteral"); err != nil {
+		return err
+	}
+	if v.Body == nil {
+		return missing("FunctionLiteral", "body")
+	}
+
+	if v.Parameters == nil {
+		v.Parameters = []*Identifier{}
+	}
+	for n, p := range v.Parameters {
+		if p == nil {
+			return missing("FunctionLiteral", fmt.Sprintf("parameters[%d]", n))
+		}
+	}
+	*fl = FunctionLiteral{Token: v.startToken(token.FUNCTION, "fn"), Parameters: v.Parameters, Body: v.Body}
+	return nil
+}
+
+func (cl *CallExpression) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Function  json.RawMessage   `json:"function"`
+		Arguments []json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("CallExpression"); err != nil {
+		return err
+	}
+
+	function, err := unmarshalExpression(v.Function, "CallExpression", "function")
+	if err != nil {
+		return err
+	}
+	arguments, err := unmarshalExpressions(v.Arguments, "CallExpression", "arguments")
+	if err != nil {
+		return err
+	}
+	*cl = CallExpression{Token: operatorToken(token.LPAREN), Function: function, Arguments: arguments, Rparen: v.endToken(token.RPAREN)}
+	return nil
+}
+
+func (al *ArrayLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Elements []json.RawMessage `json:"elements"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("ArrayLiteral"); err != nil {
+		return err
+	}
+
+	elements, err := unmarshalExpressions(v.Elements, "ArrayLiteral", "elements")
+	if err != nil {
+		return err
+	}
+	*al = ArrayLiteral{Token: v.startToken(token.LBRACKET, "["), Elements: elements, Rbracket: v.endToken(token.RBRACKET)}
+	return nil
+}
+
+func (ie *IndexExpression) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Left  json.RawMessage `json:"left"`
+		Index json.RawMessage `json:"index"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("IndexExpression"); err != nil {
+		return err
+	}
+
+	left, err := unmarshalExpression(v.Left, "IndexExpression", "left")
+	if err != nil {
+		return err
+	}
+	index, err := unmarshalExpression(v.Index, "IndexExpression", "index")
+	if err != nil {
+		return err
+	}
+	*ie = IndexExpression{Token: operatorToken(token.LBRACKET), Left: left, Index: index, Rbracket: v.endToken(token.RBRACKET)}
+	return nil
+}
+
+func (hl *HashLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonNode
+		Pairs []struct {
+			Key   json.RawMessage `json:"key"`
+			Value json.RawMessage `json:"value"`
+		} `json:"pairs"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if err := v.check("HashLiteral"); err != nil {
+		return err
+	}
+
+	pairs := []HashPair{}
+	for n, pair := range v.Pairs {
+		key, err := unmarshalExpression(pair.Key, "HashLiteral", fmt.Sprintf("pairs[%d].key", n))
+		if err != nil {
+			return err
+		}
+		value, err := unmarshalExpression(pair.Value, "HashLiteral", fmt.Sprintf("pairs[%d].value", n))
+		if err != nil {
+			return err
+		}
+		pairs = append(pairs, HashPair{Key: key, Value: value})
+	}
+	*hl = HashLiteral{Token: v.startToken(token.LBRACE, "{"), Pairs: pairs, Rbrace: v.endToken(token.RBRACE)}
+	return nil
+}
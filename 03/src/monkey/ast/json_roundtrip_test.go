@@ -0,0 +1,70 @@
+package ast_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	input := `let add = fn(a, b) { a + b };
+// a comment
+for x in [1, 2.5, "three"] {
+	if (x == 1) { continue } else if (!x) { break } else { add(x, -x)[0] }
+}
+while (true) { h = {"a": 0xff, true: [ ]}; h["a"] += 1; return h; }
+-x; "s"[0]; fn() { 1 }(); if (y) { 2 }; [3][0] = 4`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf(" parser has errors %q ", parser.Messages(p.Errors()))
+	}
+
+	data, err := json.Marshal(program)
+	if err != nil {
+		t.Fatalf(" marshal failed: %s ", err)
+	}
+
+	decoded := &ast.Program{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatalf(" unmarshal failed: %s ", err)
+	}
+
+	if decoded.String() != program.String() {
+		t.Fatalf(" decoded program differs\n got      %s\n expected %s ", decoded.String(), program.String())
+	}
+
+	again, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf(" marshal of decoded program failed: %s ", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf(" json changed\n before %s\n after  %s ", data, again)
+	}
+
+	// the tokens are rebuilt, their literal and position have to match
+	// what the parser gave every node
+	nodes := func(program *ast.Program) []string {
+		list := []string{}
+		ast.Inspect(program, func(n ast.Node) bool {
+			if n != nil {
+				list = append(list, fmt.Sprintf("%T %q %s-%s", n, n.TokenLiteral(), n.Pos(), n.End()))
+			}
+			return true
+		})
+		return list
+	}
+	got, expected := nodes(decoded), nodes(program)
+	if len(got) != len(expected) {
+		t.Fatalf(" decoded %d nodes expected %d ", len(got), len(expected))
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf(" decoded node %d is %q expected %q ", i, got[i], expected[i])
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package ast
+
+import (
+	"encoding/json"
+	"monkey/token"
+	"strconv"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	// let x = -1;
+	program := &Program{Statements: []Statement{
+		&LetStatement{
+			Token: token.Token{Type: token.LET, Literal: "let", Pos: token.Position{Offset: 0, Line: 1, Column: 1}},
+			Name: &Identifier{
+				Token: token.Token{Type: token.IDENT, Literal: "x", Pos: token.Position{Offset: 4, Line: 1, Column: 5}, End: token.Position{Offset: 5, Line: 1, Column: 6}},
+				Value: "x",
+			},
+			Value: &PrefixExpression{
+				Token:    token.Token{Type: token.MINUS, Literal: "-", Pos: token.Position{Offset: 8, Line: 1, Column: 9}},
+				Operator: "-",
+				Right: &IntegerLiteral{
+					Token: token.Token{Type: token.INT, Literal: "1", Pos: token.Position{Offset: 9, Line: 1, Column: 10}, End: token.Position{Offset: 10, Line: 1, Column: 11}},
+					Value: 1,
+				},
+			},
+		},
+	}}
+
+	data, err := json.Marshal(program)
+	if err != nil {
+		t.Fatalf(" marshal failed: %s ", err)
+	}
+
+	span := func(start, end int) string {
+		return `"span":{"start":{"offset":` + strconv.Itoa(start) + `,"line":1,"column":` + strconv.Itoa(start+1) +
+			`},"end":{"offset":` + strconv.Itoa(end) + `,"line":1,"column":` + strconv.Itoa(end+1) + `}}`
+	}
+	expected := `{"kind":"Program",` + span(0, 10) + `,"statements":[` +
+		`{"kind":"LetStatement",` + span(0, 10) + `,` +
+		`"name":{"kind":"Identifier",` + span(4, 5) + `,"value":"x"},` +
+		`"value":{"kind":"PrefixExpression",` + span(8, 10) + `,"operator":"-",` +
+		`"right":{"kind":"IntegerLiteral",` + span(9, 10) + `,"value":1,"literal":"1"}}}]}`
+
+	if string(data) != expected {
+		t.Errorf(" wrong json\n got      %s\n expected %s ", data, expected)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	program := everyNode()
+
+	data, err := json.Marshal(program)
+	if err != nil {
+		t.Fatalf(" marshal failed: %s ", err)
+	}
+
+	node, err := UnmarshalNode(data)
+	if err != nil {
+		t.Fatalf(" unmarshal failed: %s ", err)
+	}
+
+	decoded, ok := node.(*Program)
+	if !ok {
+		t.Fatalf(" decoded a %T ", node)
+	}
+	// the tokens are rebuilt, so keywords and literals are back in String
+	expected := `let a = fn(b, c) { return (d + 1); };if ((!true)) { 2 } else if (e) { 3 } else {  };` +
+		`while (f) { break; };for g in [4] { continue; };h("s", 5);((i[6]) = {j: 7})`
+	if decoded.String() != expected {
+		t.Errorf(" decoded program is %q expected %q ", decoded.String(), expected)
+	}
+
+	again, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf(" marshal of decoded program failed: %s ", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf(" json changed\n before %s\n after  %s ", data, again)
+	}
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind":"Loop"}`, `ast: unknown node kind "Loop"`},
+		{`{"value":1}`, "ast: node without kind"},
+		{`{"kind":"Program","statements":[{"kind":"Identifier","value":"x"}]}`, "ast: *ast.Identifier is not a statement"},
+		{`{"kind":"ExpressionStatement","expression":{"kind":"BreakStatement"}}`, "ast: *ast.BreakStatement is not an expression"},
+		{`{"kind":"LetStatement","name":{"kind":"Boolean"}}`, `ast: cannot unmarshal "Boolean" into Identifier`},
+		{`{"kind":"Program","statements":[null]}`, "ast: Program without statements[0]"},
+		{`{"kind":"InfixExpression","left":null,"operator":"+","right":{"kind":"Identifier","value":"a"}}`, "ast: InfixExpression without left"},
+		{`{"kind":"PrefixExpression","operator":"-"}`, "ast: PrefixExpression without right"},
+		{`{"kind":"ArrayLiteral","elements":[{"kind":"Identifier","value":"a"},null]}`, "ast: ArrayLiteral without elements[1]"},
+		{`{"kind":"LetStatement","value":{"kind":"Identifier","value":"a"}}`, "ast: LetStatement without name"},
+		{`{"kind":"WhileStatement","condition":{"kind":"Boolean","value":true},"body":null}`, "ast: WhileStatement without body"},
+		{`{"kind":"FunctionLiteral","parameters":[null],"body":{"kind":"BlockStatement","statements":[]}}`, "ast: FunctionLiteral without parameters[0]"},
+		{`{"kind":"HashLiteral","pairs":[{"key":{"kind":"Identifier","value":"a"}}]}`, "ast: HashLiteral without pairs[0].value"},
+		{`{"kind":"BlockStatement","statements":[{"kind":"ReturnStatement"}]}`, "ast: ReturnStatement without returnValue"},
+	}
+
+	for _, tt := range tests {
+		_, err := UnmarshalNode([]byte(tt.input))
+		if err == nil {
+			t.Errorf(" %s decoded without error ", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf(" %s gave error %q expected %q ", tt.input, err.Error(), tt.expected)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package ast
+
+import "fmt"
+
+type ModifierFunc func(Node) Node
+
+// Modify rewrites the tree rooted at node bottom up. The children of every
+// node are modified first, then the node itself is replaced by whatever
+// modifier returns for it. A child is only replaced when the result still
+// fits into its field, for example an expression returned for a
+// statement is ignored.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch n := node.(type) {
+	case *Program:
+		n.Statements = modifyStatements(n.Statements, modifier)
+
+	case *LetStatement:
+		n.Name = modifyIdentifier(n.Name, modifier)
+		n.Value = modifyExpression(n.Value, modifier)
+
+	case *ReturnStatement:
+		n.ReturnValue = modifyExpression(n.ReturnValue, modifier)
+
+	case *ExpressionStatement:
+		n.Expression = modifyExpression(n.Expression, modifier)
+
+	case *BlockStatement:
+		n.Statements = modifyStatements(n.Statements, modifier)
+
+	case *WhileStatement:
+		n.Condition = modifyExpression(n.Condition, modifier)
+		n.Body = modifyBlock(n.Body, modifier)
+
+	case *ForStatement:
+		n.Variable = modifyIdentifier(n.Variable, modifier)
+		n.Iterable = modifyExpression(n.Iterable, modifier)
+		n.Body = modifyBlock(n.Body, modifier)
+
+	case *PrefixExpression:
+		n.Right = modifyExpression(n.Right, modifier)
+
+	case *InfixExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Right = modifyExpression(n.Right, modifier)
+
+	case *AssignExpression:
+		n.Target = modifyExpression(n.Target, modifier)
+		n.Value = modifyExpression(n.Value, modifier)
+
+	case *IfExpression:
+		n.Condition = modifyExpression(n.Condition, modifier)
+		n.Consequence = modifyBlock(n.Consequence, modifier)
+		for _, ei := range n.ElseIfs {
+			ei.Condition = modifyExpression(ei.Condition, modifier)
+			ei.Consequence = modifyBlock(ei.Consequence, modifier)
+		}
+		n.Alternative = modifyBlock(n.Alternative, modifier)
+
+	case *FunctionLiteral:
+		for i, p := range n.Parameters {
+			n.Parameters[i] = modifyIdentifier(p, modifier)
+		}
+		n.Body = modifyBlock(n.Body, modifier)
+
+	case *CallExpression:
+		n.Function = modifyExpression(n.Function, modifier)
+		n.Arguments = modifyExpressions(n.Arguments, modifier)
+
+	case *ArrayLiteral:
+		n.Elements = modifyExpressions(n.Elements, modifier)
+
+	case *IndexExpression:
+		n.Left = modifyExpression(n.Left, modifier)
+		n.Index = modifyExpression(n.Index, modifier)
+
+	case *HashLiteral:
+		for i, pair := range n.Pairs {
+			n.Pairs[i] = HashPair{
+				Key:   modifyExpression(pair.Key, modifier),
+				Value: modifyExpression(pair.Value, modifier),
+			}
+		}
+
+	case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean,
+		*BreakStatement, *ContinueStatement:
+		// leaves
+
+	default:
+		panic(fmt.Sprintf("ast.Modify: unexpected node type %T", n))
+	}
+
+	return modifier(node)
+}
+
+func modifyStatements(list []Statement, modifier ModifierFunc) []Statement {
+	for i, s := range list {
+		if s == nil {
+			continue
+		}
+		if modified, ok := Modify(s, modifier).(Statement); ok {
+			list[i] = modified
+		}
+	}
+	return list
+}
+
+func modifyExpressions(list []Expression, modifier ModifierFunc) []Expression {
+	for i, e := range list {
+		list[i] = modifyExpression(e, modifier)
+	}
+	return list
+}
+
+func modifyExpression(e Expression, modifier ModifierFunc) Expression {
+	if e == nil {
+		return nil
+	}
+	if modified, ok := Modify(e, modifier).(Expression); ok {
+		return modified
+	}
+	return e
+}
+
+func modifyBlock(b *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if b == nil {
+		return nil
+	}
+	if modified, ok := Modify(b, modifier).(*BlockStatement); ok {
+		return modified
+	}
+	return b
+}
+
+func modifyIdentifier(i *Identifier, modifier ModifierFunc) *Identifier {
+	if i == nil {
+		return nil
+	}
+	if modified, ok := Modify(i, modifier).(*Identifier); ok {
+		return modified
+	}
+	return i
+}
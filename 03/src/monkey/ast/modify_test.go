@@ -0,0 +1,147 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return integer(1) }
+	two := func() Expression { return integer(2) }
+
+	turnOneIntoTwo := func(node Node) Node {
+		literal, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+
+		if literal.Value != 1 {
+			return node
+		}
+
+		return two()
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{
+			&Program{Statements: []Statement{exprStmt(one())}},
+			&Program{Statements: []Statement{exprStmt(two())}},
+		},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&InfixExpression{Left: two(), Operator: "+", Right: one()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&IfExpression{
+				Condition:   one(),
+				Consequence: block(exprStmt(one())),
+				ElseIfs:     []*ElseIf{{Condition: one(), Consequence: block(exprStmt(one()))}},
+				Alternative: block(exprStmt(one())),
+			},
+			&IfExpression{
+				Condition:   two(),
+				Consequence: block(exprStmt(two())),
+				ElseIfs:     []*ElseIf{{Condition: two(), Consequence: block(exprStmt(two()))}},
+				Alternative: block(exprStmt(two())),
+			},
+		},
+		{
+			&ReturnStatement{ReturnValue: one()},
+			&ReturnStatement{ReturnValue: two()},
+		},
+		{
+			&LetStatement{Name: ident("x"), Value: one()},
+			&LetStatement{Name: ident("x"), Value: two()},
+		},
+		{
+			&FunctionLiteral{Parameters: []*Identifier{}, Body: block(exprStmt(one()))},
+			&FunctionLiteral{Parameters: []*Identifier{}, Body: block(exprStmt(two()))},
+		},
+		{
+			&CallExpression{Function: ident("f"), Arguments: []Expression{one(), two()}},
+			&CallExpression{Function: ident("f"), Arguments: []Expression{two(), two()}},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+		{
+			&HashLiteral{Pairs: []HashPair{{Key: one(), Value: one()}}},
+			&HashLiteral{Pairs: []HashPair{{Key: two(), Value: two()}}},
+		},
+		{
+			&WhileStatement{Condition: one(), Body: block(exprStmt(one()))},
+			&WhileStatement{Condition: two(), Body: block(exprStmt(two()))},
+		},
+		{
+			&ForStatement{Variable: ident("x"), Iterable: one(), Body: block(exprStmt(one()))},
+			&ForStatement{Variable: ident("x"), Iterable: two(), Body: block(exprStmt(two()))},
+		},
+		{
+			&AssignExpression{Target: &IndexExpression{Left: ident("a"), Index: one()}, Operator: "=", Value: one()},
+			&AssignExpression{Target: &IndexExpression{Left: ident("a"), Index: two()}, Operator: "=", Value: two()},
+		},
+	}
+
+	for _, tt := range tests {
+		modified := Modify(tt.input, turnOneIntoTwo)
+
+		if !reflect.DeepEqual(modified, tt.expected) {
+			t.Errorf(" not equal got=%#v, want=%#v ", modified, tt.expected)
+		}
+	}
+}
+
+func TestModifyRenamesParameters(t *testing.T) {
+	fn := &FunctionLiteral{
+		Parameters: []*Identifier{ident("x"), ident("y")},
+		Body:       block(exprStmt(&InfixExpression{Left: ident("x"), Operator: "+", Right: ident("y")})),
+	}
+
+	Modify(fn, func(node Node) Node {
+		if id, ok := node.(*Identifier); ok && id.Value == "x" {
+			return ident("renamed")
+		}
+		return node
+	})
+
+	if fn.Parameters[0].Value != "renamed" {
+		t.Errorf(" parameter was not renamed got %s ", fn.Parameters[0].Value)
+	}
+	if fn.Body.String() != "{ (renamed + y) }" {
+		t.Errorf(" body was not modified got %s ", fn.Body.String())
+	}
+}
+
+func TestModifyKeepsNodesThatDoNotFit(t *testing.T) {
+	program := &Program{Statements: []Statement{exprStmt(integer(1))}}
+
+	// a statement cannot stand in for the integer literal
+	Modify(program, func(node Node) Node {
+		if _, ok := node.(*IntegerLiteral); ok {
+			return &BreakStatement{}
+		}
+		return node
+	})
+
+	stmt := program.Statements[0].(*ExpressionStatement)
+	if _, ok := stmt.Expression.(*IntegerLiteral); !ok {
+		t.Errorf(" expression was replaced with %T ", stmt.Expression)
+	}
+}
@@ -0,0 +1,156 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is called for every node found by Walk. If the
+// visitor w returned by Visit is not nil, Walk visits each of the children
+// of node with w and calls w.Visit(nil) afterwards.
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth first order, children
+// are visited in the order they appear in the source. Missing children,
+// like the value of a let statement that failed to parse, are skipped.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	for _, f := range fieldsOf(node) {
+		if f.node != nil {
+			Walk(v, f.node)
+		}
+	}
+
+	v.Visit(nil)
+}
+
+// field is a child of a node together with the name of the field it is
+// stored in, missing children are nil
+type field struct {
+	name string
+	node Node
+}
+
+// fieldsOf returns the children of n in source order, named after their
+// fields. Lists get an index, the parts of else if branches and hash pairs
+// are named after the branch or pair. Walk, Inspect, DOT and SExpr share
+// it, Modify, the JSON encoding and the format package keep their own
+// switch since they need the typed fields.
+func fieldsOf(n Node) []field {
+	var fields []field
+	add := func(name string, node Node) {
+		fields = append(fields, field{name, node})
+	}
+	expression := func(name string, e Expression) {
+		if e == nil {
+			add(name, nil)
+		} else {
+			add(name, e)
+		}
+	}
+	block := func(name string, b *BlockStatement) {
+		if b == nil {
+			add(name, nil)
+		} else {
+			add(name, b)
+		}
+	}
+	identifier := func(name string, i *Identifier) {
+		if i == nil {
+			add(name, nil)
+		} else {
+			add(name, i)
+		}
+	}
+	statements := func(list []Statement) {
+		for i, s := range list {
+			add(fmt.Sprintf("statements[%d]", i), s)
+		}
+	}
+	expressions := func(name string, list []Expression) {
+		for i, e := range list {
+			expression(fmt.Sprintf("%s[%d]", name, i), e)
+		}
+	}
+
+	switch n := n.(type) {
+	case *Program:
+		statements(n.Statements)
+	case *LetStatement:
+		identifier("name", n.Name)
+		expression("value", n.Value)
+	case *ReturnStatement:
+		expression("returnValue", n.ReturnValue)
+	case *ExpressionStatement:
+		expression("expression", n.Expression)
+	case *BlockStatement:
+		statements(n.Statements)
+	case *WhileStatement:
+		expression("condition", n.Condition)
+		block("body", n.Body)
+	case *ForStatement:
+		identifier("variable", n.Variable)
+		expression("iterable", n.Iterable)
+		block("body", n.Body)
+	case *PrefixExpression:
+		expression("right", n.Right)
+	case *InfixExpression:
+		expression("left", n.Left)
+		expression("right", n.Right)
+	case *AssignExpression:
+		expression("target", n.Target)
+		expression("value", n.Value)
+	case *IfExpression:
+		expression("condition", n.Condition)
+		block("consequence", n.Consequence)
+		for i, ei := range n.ElseIfs {
+			expression(fmt.Sprintf("elseIfs[%d].condition", i), ei.Condition)
+			block(fmt.Sprintf("elseIfs[%d].consequence", i), ei.Consequence)
+		}
+		if n.Alternative != nil {
+			add("alternative", n.Alternative)
+		}
+	case *FunctionLiteral:
+		for i, p := range n.Parameters {
+			identifier(fmt.Sprintf("parameters[%d]", i), p)
+		}
+		block("body", n.Body)
+	case *CallExpression:
+		expression("function", n.Function)
+		expressions("arguments", n.Arguments)
+	case *ArrayLiteral:
+		expressions("elements", n.Elements)
+	case *IndexExpression:
+		expression("left", n.Left)
+		expression("index", n.Index)
+	case *HashLiteral:
+		for i, pair := range n.Pairs {
+			expression(fmt.Sprintf("pairs[%d].key", i), pair.Key)
+			expression(fmt.Sprintf("pairs[%d].value", i), pair.Value)
+		}
+	case *Identifier, *IntegerLiteral, *FloatLiteral, *StringLiteral, *Boolean,
+		*BreakStatement, *ContinueStatement:
+		// leaves
+	default:
+		panic(fmt.Sprintf("ast: unexpected node type %T", n))
+	}
+	return fields
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree like Walk and calls f for every node. The
+// children of a node are skipped when f returns false. After the children
+// f is called with nil.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,164 @@
+package ast
+
+import (
+	"fmt"
+	"monkey/token"
+	"reflect"
+	"testing"
+)
+
+func ident(name string) *Identifier {
+	return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+
+func integer(value int64) *IntegerLiteral {
+	return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: fmt.Sprint(value)}, Value: value}
+}
+
+func block(statements ...Statement) *BlockStatement {
+	return &BlockStatement{Token: token.Token{Type: token.LBRACE, Literal: "{"}, Statements: statements}
+}
+
+func exprStmt(e Expression) *ExpressionStatement {
+	return &ExpressionStatement{Expression: e}
+}
+
+// everyNode builds a program that contains every node type, the
+// identifiers and integers are numbered in source order
+func everyNode() *Program {
+	return &Program{
+		Statements: []Statement{
+			&LetStatement{Name: ident("a"), Value: &FunctionLiteral{
+				Parameters: []*Identifier{ident("b"), ident("c")},
+				Body: block(
+					&ReturnStatement{ReturnValue: &InfixExpression{Left: ident("d"), Operator: "+", Right: integer(1)}},
+				),
+			}},
+			exprStmt(&IfExpression{
+				Condition:   &PrefixExpression{Operator: "!", Right: &Boolean{Value: true}},
+				Consequence: block(exprStmt(integer(2))),
+				ElseIfs: []*ElseIf{
+					{Condition: ident("e"), Consequence: block(exprStmt(integer(3)))},
+				},
+				Alternative: block(exprStmt(&FloatLiteral{Value: 1.5})),
+			}),
+			&WhileStatement{Condition: ident("f"), Body: block(&BreakStatement{})},
+			&ForStatement{Variable: ident("g"), Iterable: &ArrayLiteral{Elements: []Expression{integer(4)}}, Body: block(&ContinueStatement{})},
+			exprStmt(&CallExpression{Function: ident("h"), Arguments: []Expression{&StringLiteral{Value: "s"}, integer(5)}}),
+			exprStmt(&AssignExpression{
+				Target:   &IndexExpression{Left: ident("i"), Index: integer(6)},
+				Operator: "=",
+				Value:    &HashLiteral{Pairs: []HashPair{{Key: ident("j"), Value: integer(7)}}},
+			}),
+		},
+	}
+}
+
+func TestInspectVisitsEveryNode(t *testing.T) {
+	var visited []string
+	Inspect(everyNode(), func(n Node) bool {
+		switch n := n.(type) {
+		case nil:
+		case *Identifier:
+			visited = append(visited, n.Value)
+		case *IntegerLiteral:
+			visited = append(visited, fmt.Sprint(n.Value))
+		default:
+			visited = append(visited, reflect.TypeOf(n).Elem().Name())
+		}
+		return true
+	})
+
+	expected := []string{
+		"Program",
+		"LetStatement", "a", "FunctionLiteral", "b", "c", "BlockStatement",
+		"ReturnStatement", "InfixExpression", "d", "1",
+		"ExpressionStatement", "IfExpression", "PrefixExpression", "Boolean",
+		"BlockStatement", "ExpressionStatement", "2",
+		"e", "BlockStatement", "ExpressionStatement", "3",
+		"BlockStatement", "ExpressionStatement", "FloatLiteral",
+		"WhileStatement", "f", "BlockStatement", "BreakStatement",
+		"ForStatement", "g", "ArrayLiteral", "4", "BlockStatement", "ContinueStatement",
+		"ExpressionStatement", "CallExpression", "h", "StringLiteral", "5",
+		"ExpressionStatement", "AssignExpression", "IndexExpression", "i", "6",
+		"HashLiteral", "j", "7",
+	}
+
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf(" visited wrong nodes\n got      %q\n expected %q ", visited, expected)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	var identifiers []string
+	Inspect(everyNode(), func(n Node) bool {
+		if id, ok := n.(*Identifier); ok {
+			identifiers = append(identifiers, id.Value)
+		}
+		// do not look into function bodies
+		_, isFunction := n.(*FunctionLiteral)
+		return !isFunction
+	})
+
+	expected := []string{"a", "e", "f", "g", "h", "i", "j"}
+	if !reflect.DeepEqual(identifiers, expected) {
+		t.Errorf(" expected identifiers %q got %q ", expected, identifiers)
+	}
+}
+
+type depthVisitor struct {
+	depth    int
+	maxDepth *int
+	nils     *int
+}
+
+func (v depthVisitor) Visit(n Node) Visitor {
+	if n == nil {
+		*v.nils += 1
+		return nil
+	}
+	if v.depth > *v.maxDepth {
+		*v.maxDepth = v.depth
+	}
+	return depthVisitor{depth: v.depth + 1, maxDepth: v.maxDepth, nils: v.nils}
+}
+
+func TestWalkCallsVisitWithNil(t *testing.T) {
+	maxDepth, nils := 0, 0
+	program := &Program{Statements: []Statement{
+		exprStmt(&InfixExpression{Left: integer(1), Operator: "+", Right: &PrefixExpression{Operator: "-", Right: integer(2)}}),
+	}}
+
+	Walk(depthVisitor{maxDepth: &maxDepth, nils: &nils}, program)
+
+	// Program, ExpressionStatement, InfixExpression, PrefixExpression, 2
+	if maxDepth != 4 {
+		t.Errorf(" expected max depth 4 got %d ", maxDepth)
+	}
+	// one nil for every node visited
+	if nils != 6 {
+		t.Errorf(" expected 6 calls with nil got %d ", nils)
+	}
+}
+
+func TestWalkSkipsMissingChildren(t *testing.T) {
+	program := &Program{Statements: []Statement{
+		&LetStatement{Name: ident("x")},
+		&ReturnStatement{},
+		exprStmt(&InfixExpression{Left: integer(1), Operator: "+"}),
+		exprStmt(&IfExpression{Condition: ident("y")}),
+		exprStmt(&FunctionLiteral{}),
+	}}
+
+	count := 0
+	Inspect(program, func(n Node) bool {
+		if n != nil {
+			count += 1
+		}
+		return true
+	})
+
+	if count != 12 {
+		t.Errorf(" expected 12 nodes got %d ", count)
+	}
+}
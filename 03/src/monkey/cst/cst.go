@@ -0,0 +1,413 @@
+// Package cst builds a lossless concrete syntax tree on top of the ast.
+// Every byte of the source ends up in exactly one token of the tree,
+// whitespace, comments and semicolons are kept as trivia on the tokens
+// next to them. Printing a tree that was not modified gives back the
+// source byte for byte, so tools can edit the tokens of a node and print
+// the file without disturbing the rest of it.
+//
+// The tree is built from a second pass of the lexer after the normal
+// parse, the parser itself does not know about it.
+package cst
+
+import (
+	"bytes"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"strings"
+)
+
+type TriviaKind int
+
+const (
+	Whitespace TriviaKind = iota
+	Comment
+	// the parser does not keep semicolons in the ast, so they are
+	// trivia like the whitespace around them
+	Semicolon
+)
+
+func (k TriviaKind) String() string {
+	switch k {
+	case Whitespace:
+		return "whitespace"
+	case Comment:
+		return "comment"
+	case Semicolon:
+		return "semicolon"
+	default:
+		return "unknown"
+	}
+}
+
+// Trivia is source text that carries no meaning for the parser.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+}
+
+// Token is a token of the source with its raw text and the trivia around
+// it. Leading trivia starts on the line after the previous token, trailing
+// trivia is the rest of the line of the token up to and including the
+// newline. Pos is where the token was found and is not updated when the
+// tree is edited.
+type Token struct {
+	Type     token.TokenType
+	Text     string
+	Pos      token.Position
+	Leading  []Trivia
+	Trailing []Trivia
+}
+
+// Element is a *Node or a *Token.
+type Element interface {
+	// String returns the source of the element including its trivia
+	String() string
+	writeTo(buf *bytes.Buffer)
+}
+
+// Node is the concrete form of an ast node. Children holds its tokens and
+// the nodes of its ast children in source order, the parentheses around a
+// grouped expression belong to the expression.
+type Node struct {
+	AST      ast.Node
+	Children []Element
+}
+
+// File is the tree of a whole source file. Root belongs to the
+// *ast.Program and also holds the tokens of statements the parser dropped
+// because of errors, EOF carries the trivia at the end of the file.
+type File struct {
+	Program *ast.Program
+	Root    *Node
+	EOF     *Token
+	Errors  []parser.Diagnostic
+
+	nodes map[ast.Node]*Node
+}
+
+// Parse parses src and builds its concrete syntax tree. The tree is built
+// even when there are errors, it then prints back the source as well.
+func Parse(filename string, src []byte) *File {
+	p := parser.New(lexer.NewFile(filename, string(src)))
+	program := p.ParseProgram()
+
+	b := &builder{src: src, extents: map[ast.Node]extent{}, nodes: map[ast.Node]*Node{}}
+	b.scan(filename)
+	b.markSyntacticParens(program)
+	b.extent(program)
+	b.extents[program] = extent{start: 0, end: len(src)}
+
+	root := b.node(program)
+	return &File{Program: program, Root: root, EOF: b.eof, Errors: p.Errors(), nodes: b.nodes}
+}
+
+// Bytes prints the tree.
+func (f *File) Bytes() []byte {
+	var buf bytes.Buffer
+	f.Root.writeTo(&buf)
+	f.EOF.writeTo(&buf)
+	return buf.Bytes()
+}
+
+func (f *File) String() string {
+	return string(f.Bytes())
+}
+
+// Find returns the concrete node of an ast node of f.Program, nil for
+// nodes that are not part of it.
+func (f *File) Find(node ast.Node) *Node {
+	return f.nodes[node]
+}
+
+func (n *Node) String() string {
+	var buf bytes.Buffer
+	n.writeTo(&buf)
+	return buf.String()
+}
+
+func (n *Node) writeTo(buf *bytes.Buffer) {
+	for _, child := range n.Children {
+		child.writeTo(buf)
+	}
+}
+
+// Tokens returns the tokens of n and of all nodes below it in source
+// order.
+func (n *Node) Tokens() []*Token {
+	tokens := []*Token{}
+	for _, child := range n.Children {
+		switch child := child.(type) {
+		case *Token:
+			tokens = append(tokens, child)
+		case *Node:
+			tokens = append(tokens, child.Tokens()...)
+		}
+	}
+	return tokens
+}
+
+// Leading returns the leading trivia of the first token of n.
+func (n *Node) Leading() []Trivia {
+	tokens := n.Tokens()
+	if len(tokens) == 0 {
+		return nil
+	}
+	return tokens[0].Leading
+}
+
+// Trailing returns the trailing trivia of the last token of n.
+func (n *Node) Trailing() []Trivia {
+	tokens := n.Tokens()
+	if len(tokens) == 0 {
+		return nil
+	}
+	return tokens[len(tokens)-1].Trailing
+}
+
+func (t *Token) String() string {
+	var buf bytes.Buffer
+	t.writeTo(&buf)
+	return buf.String()
+}
+
+func (t *Token) writeTo(buf *bytes.Buffer) {
+	for _, tr := range t.Leading {
+		buf.WriteString(tr.Text)
+	}
+	buf.WriteString(t.Text)
+	for _, tr := range t.Trailing {
+		buf.WriteString(tr.Text)
+	}
+}
+
+// extent is the byte range of the source a node covers
+type extent struct {
+	start, end int
+}
+
+type builder struct {
+	src []byte
+
+	// the significant tokens, everything but trivia and EOF
+	tokens []*Token
+	eof    *Token
+	// next is the first token not yet put into the tree
+	next int
+
+	// index of the token starting or ending at a byte offset
+	startsAt map[int]int
+	endsAt   map[int]int
+	// matching ) for every (, -1 when there is none
+	match []int
+	// ( that are part of a call, condition or parameter list instead of
+	// grouping an expression
+	syntactic map[int]bool
+
+	extents map[ast.Node]extent
+	nodes   map[ast.Node]*Node
+}
+
+// scan lexes the source again with comments and splits it into tokens
+// and trivia
+func (b *builder) scan(filename string) {
+	l := lexer.NewFile(filename, string(b.src))
+	l.SetMode(lexer.ScanComments)
+
+	b.startsAt = map[int]int{}
+	b.endsAt = map[int]int{}
+
+	var last *Token
+	var pending []Trivia
+	// trailing is set until the line of the last token ends
+	trailing := false
+
+	attach := func(tr Trivia) {
+		if trailing {
+			last.Trailing = append(last.Trailing, tr)
+		} else {
+			pending = append(pending, tr)
+		}
+	}
+
+	offset := 0
+	for {
+		tok := l.NextToken()
+
+		// the lexer only skips whitespace between tokens
+		if gap := string(b.src[offset:tok.Pos.Offset]); gap != "" {
+			if i := strings.IndexByte(gap, '\n'); trailing && i >= 0 {
+				attach(Trivia{Kind: Whitespace, Text: gap[:i+1]})
+				trailing = false
+				gap = gap[i+1:]
+			}
+			if gap != "" {
+				attach(Trivia{Kind: Whitespace, Text: gap})
+			}
+		}
+		offset = tok.End.Offset
+		text := string(b.src[tok.Pos.Offset:tok.End.Offset])
+
+		switch tok.Type {
+		case token.EOF:
+			b.eof = &Token{Type: token.EOF, Pos: tok.Pos, Leading: pending}
+			b.matchParens()
+			return
+		case token.COMMENT:
+			attach(Trivia{Kind: Comment, Text: text})
+		case token.SEMICOLON:
+			attach(Trivia{Kind: Semicolon, Text: text})
+		default:
+			last = &Token{Type: tok.Type, Text: text, Pos: tok.Pos, Leading: pending}
+			pending = nil
+			trailing = true
+
+			b.startsAt[tok.Pos.Offset] = len(b.tokens)
+			b.endsAt[tok.End.Offset] = len(b.tokens)
+			b.tokens = append(b.tokens, last)
+		}
+	}
+}
+
+func (b *builder) matchParens() {
+	b.match = make([]int, len(b.tokens))
+	open := []int{}
+	for i, tok := range b.tokens {
+		b.match[i] = -1
+		switch tok.Type {
+		case token.LPAREN:
+			open = append(open, i)
+		case token.RPAREN:
+			if len(open) > 0 {
+				b.match[open[len(open)-1]] = i
+				open = open[:len(open)-1]
+			}
+		}
+	}
+}
+
+// markSyntacticParens finds the ( that the ast knows about, all others
+// group an expression
+func (b *builder) markSyntacticParens(program *ast.Program) {
+	b.syntactic = map[int]bool{}
+	// the ( right after a keyword token
+	after := func(tok token.Token) {
+		if i, ok := b.startsAt[tok.Pos.Offset]; ok {
+			b.syntactic[i+1] = true
+		}
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.CallExpression:
+			if i, ok := b.startsAt[node.Token.Pos.Offset]; ok {
+				b.syntactic[i] = true
+			}
+		case *ast.FunctionLiteral:
+			after(node.Token)
+		case *ast.WhileStatement:
+			after(node.Token)
+		case *ast.IfExpression:
+			after(node.Token)
+			for _, ei := range node.ElseIfs {
+				// the else if branch starts at the else
+				if i, ok := b.startsAt[ei.Token.Pos.Offset]; ok {
+					b.syntactic[i+2] = true
+				}
+			}
+		}
+		return true
+	})
+}
+
+// children returns the direct children of node in source order
+func children(node ast.Node) []ast.Node {
+	list := []ast.Node{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		if n == node {
+			return true
+		}
+		list = append(list, n)
+		return false
+	})
+	return list
+}
+
+// extent computes the range covered by node and everything below it,
+// including the parentheses that group an expression
+func (b *builder) extent(node ast.Node) extent {
+	ext := extent{start: -1, end: -1}
+	if node.Pos().IsValid() {
+		ext.start = node.Pos().Offset
+	}
+	if node.End().IsValid() {
+		ext.end = node.End().Offset
+	}
+
+	for _, child := range children(node) {
+		c := b.extent(child)
+		if ext.start < 0 || (c.start >= 0 && c.start < ext.start) {
+			ext.start = c.start
+		}
+		if c.end > ext.end {
+			ext.end = c.end
+		}
+	}
+
+	if _, ok := node.(ast.Expression); ok {
+		ext = b.group(ext)
+	}
+
+	b.extents[node] = ext
+	return ext
+}
+
+// group widens ext over the pairs of grouping parentheses wrapped around
+// it
+func (b *builder) group(ext extent) extent {
+	for {
+		first, ok := b.startsAt[ext.start]
+		if !ok || first == 0 {
+			return ext
+		}
+		last, ok := b.endsAt[ext.end]
+		if !ok {
+			return ext
+		}
+
+		open := first - 1
+		if b.tokens[open].Type != token.LPAREN || b.syntactic[open] || b.match[open] != last+1 {
+			return ext
+		}
+		close := b.tokens[last+1]
+		ext = extent{start: b.tokens[open].Pos.Offset, end: close.Pos.Offset + len(close.Text)}
+	}
+}
+
+// node builds the concrete node of n, taking the tokens before each child
+// and after the last one up to the end of its extent
+func (b *builder) node(n ast.Node) *Node {
+	node := &Node{AST: n, Children: []Element{}}
+	b.nodes[n] = node
+
+	for _, child := range children(n) {
+		start := b.extents[child].start
+		for b.next < len(b.tokens) && b.tokens[b.next].Pos.Offset < start {
+			node.Children = append(node.Children, b.tokens[b.next])
+			b.next += 1
+		}
+		node.Children = append(node.Children, b.node(child))
+	}
+
+	end := b.extents[n].end
+	for b.next < len(b.tokens) && b.tokens[b.next].Pos.Offset < end {
+		node.Children = append(node.Children, b.tokens[b.next])
+		b.next += 1
+	}
+
+	return node
+}
@@ -0,0 +1,188 @@
+package cst
+
+import (
+	"monkey/ast"
+	"monkey/token"
+	"reflect"
+	"testing"
+)
+
+var sources = []string{
+	"",
+	"   \n\n",
+	"let x = 5;",
+	"let   add = fn(a,b){a+b};;\n\n\nadd(1 ,2)",
+	"// leading comment\nlet x = 1; // trailing\n/* block\n   comment */ x\n",
+	"if (x) { 1 } else if ((y)) { 2 } else { /* empty */ };\n-(a + b) * ((c))",
+	"while (i < 3) {\r\n\ti += 1;\r\n\tif (i == 2) { break }\r\n}\r\n",
+	"for x in [1, 2,] { continue; } {\"a\": 1, 2: [3]}[\"a\"]",
+	"let s = \"a\\tb\\\"c\" + \"\\u{1F600}\"; 0xff + 1_000 + 1.5e3",
+	"let = 5;\nlet y = (1 + ;\nadd(1, 2\n@ \xff \"unterminated",
+	"fn(x { x }\n/* never closed",
+	"f(x)(y)[z] = (a = b) += c",
+}
+
+func TestPrintIsLossless(t *testing.T) {
+	for _, src := range sources {
+		f := Parse("", []byte(src))
+		if f.String() != src {
+			t.Errorf(" tree of %q prints as %q ", src, f.String())
+		}
+	}
+}
+
+func TestEveryNodeIsInTheTree(t *testing.T) {
+	for _, src := range sources {
+		f := Parse("", []byte(src))
+		ast.Inspect(f.Program, func(n ast.Node) bool {
+			if n != nil && f.Find(n) == nil {
+				t.Errorf(" %T of %q is missing from the tree ", n, src)
+			}
+			return true
+		})
+	}
+}
+
+func TestNodeText(t *testing.T) {
+	src := "let y = -(a + b) * f((c), d);\nif ((y)) { y }"
+	f := Parse("", []byte(src))
+
+	texts := map[string]string{}
+	ast.Inspect(f.Program, func(n ast.Node) bool {
+		if e, ok := n.(ast.Expression); ok {
+			node := f.Find(e)
+			texts[e.String()] = text(node.Tokens())
+		}
+		return true
+	})
+
+	expected := map[string]string{
+		"(a + b)":                "(a+b)",
+		"(-(a + b))":             "-(a+b)",
+		"((-(a + b)) * f(c, d))": "-(a+b)*f((c),d)",
+		"f(c, d)":                "f((c),d)",
+		"f":                      "f",
+		"c":                      "(c)",
+		"d":                      "d",
+		"y":                      "y",
+		"if (y) { y }":           "if((y)){y}",
+		"a":                      "a",
+		"b":                      "b",
+	}
+
+	for str, expectedText := range expected {
+		if texts[str] != expectedText {
+			t.Errorf(" tokens of %s are %q expected %q ", str, texts[str], expectedText)
+		}
+	}
+}
+
+// text joins the token texts without trivia
+func text(tokens []*Token) string {
+	s := ""
+	for _, tok := range tokens {
+		s += tok.Text
+	}
+	return s
+}
+
+func TestTrivia(t *testing.T) {
+	src := "// header\n\nlet x = 1; // one\n  x  ;\n// end\n"
+	f := Parse("", []byte(src))
+
+	tokens := f.Root.Tokens()
+	if text(tokens) != "letx=1x" {
+		t.Fatalf(" wrong tokens %q ", text(tokens))
+	}
+
+	tests := []struct {
+		tok      *Token
+		leading  []Trivia
+		trailing []Trivia
+	}{
+		{
+			tokens[0],
+			[]Trivia{{Comment, "// header"}, {Whitespace, "\n\n"}},
+			[]Trivia{{Whitespace, " "}},
+		},
+		{
+			tokens[3],
+			nil,
+			[]Trivia{{Semicolon, ";"}, {Whitespace, " "}, {Comment, "// one"}, {Whitespace, "\n"}},
+		},
+		{
+			tokens[4],
+			[]Trivia{{Whitespace, "  "}},
+			[]Trivia{{Whitespace, "  "}, {Semicolon, ";"}, {Whitespace, "\n"}},
+		},
+		{
+			f.EOF,
+			[]Trivia{{Comment, "// end"}, {Whitespace, "\n"}},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.tok.Leading, tt.leading) {
+			t.Errorf(" leading trivia of %q is %q expected %q ", tt.tok.Text, tt.tok.Leading, tt.leading)
+		}
+		if !reflect.DeepEqual(tt.tok.Trailing, tt.trailing) {
+			t.Errorf(" trailing trivia of %q is %q expected %q ", tt.tok.Text, tt.tok.Trailing, tt.trailing)
+		}
+	}
+
+	let := f.Find(f.Program.Statements[0])
+	if len(let.Leading()) != 2 || len(let.Trailing()) != 4 {
+		t.Errorf(" let statement has trivia %q and %q ", let.Leading(), let.Trailing())
+	}
+}
+
+func TestEditKeepsLayout(t *testing.T) {
+	src := "let  total = 0; // sum\nfor x in xs {\n\ttotal += x; /* add */\n}\ntotal\n"
+	f := Parse("", []byte(src))
+
+	// rename every use of total
+	ast.Inspect(f.Program, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Identifier); ok && id.Value == "total" {
+			for _, tok := range f.Find(id).Tokens() {
+				if tok.Type == token.IDENT {
+					tok.Text = "sum"
+				}
+			}
+		}
+		return true
+	})
+
+	expected := "let  sum = 0; // sum\nfor x in xs {\n\tsum += x; /* add */\n}\nsum\n"
+	if f.String() != expected {
+		t.Errorf(" edited tree prints as %q expected %q ", f.String(), expected)
+	}
+}
+
+func TestErrorsAreKept(t *testing.T) {
+	f := Parse("test.mk", []byte("let = 5;\nlet y = 1;"))
+	if len(f.Errors) != 1 || f.Errors[0].Error() != "test.mk:1:5: expected IDENT, got =" {
+		t.Errorf(" wrong errors %q ", f.Errors)
+	}
+	if len(f.Program.Statements) != 1 {
+		t.Fatalf(" expected one statement got %d ", len(f.Program.Statements))
+	}
+
+	// the tokens of the dropped statement stay with the root
+	first := f.Root.Children[0]
+	if tok, ok := first.(*Token); !ok || tok.Text != "let" {
+		t.Errorf(" expected the dropped let first got %q ", first.String())
+	}
+}
+
+func FuzzPrintIsLossless(f *testing.F) {
+	for _, src := range sources {
+		f.Add(src)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		if printed := Parse("", []byte(src)).String(); printed != src {
+			t.Fatalf(" tree of %q prints as %q ", src, printed)
+		}
+	})
+}
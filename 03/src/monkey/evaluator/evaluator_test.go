@@ -0,0 +1,789 @@
+package evaluator
+
+import (
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"testing"
+)
+
+func testEval(input string) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+
+	return Eval(program, env)
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	result, ok := obj.(*object.Integer)
+	if !ok {
+		t.Errorf(" object is not Integer got %T (%+v) ", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf(" %d != %d ", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
+	result, ok := obj.(*object.Boolean)
+	if !ok {
+		t.Errorf(" object is not Boolean got %T (%+v) ", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf(" %t != %t ", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testNullObject(t *testing.T, obj object.Object) bool {
+	if obj != NULL {
+		t.Errorf(" object is not NULL got %T (%+v) ", obj, obj)
+		return false
+	}
+
+	return true
+}
+
+func TestEvalIntegerExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"5", 5},
+		{"10", 10},
+		{"-5", -5},
+		{"-10", -10},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * 2 * 2 * 2 * 2", 32},
+		{"-50 + 100 + -50", 0},
+		{"5 * 2 + 10", 20},
+		{"5 + 2 * 10", 25},
+		{"20 + 2 * -10", 0},
+		{"50 / 2 * 2 + 10", 60},
+		{"2 * (5 + 10)", 30},
+		{"3 * 3 * 3 + 10", 37},
+		{"3 * (3 * 3) + 10", 37},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"10 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 + 10 % 4 * 3", 8},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf(" object is not Float got %T (%+v) ", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf(" %g != %g ", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func TestEvalNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"0xff + 0o10 + 0b11", int64(266)},
+		{"1_000 * 3", int64(3000)},
+		{"1.5", 1.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3.0},
+		{"1 + 0.5", 1.5},
+		{"3 / 2.0", 1.5},
+		{"2.5 * 2", 5.0},
+		{"1e3 - 1", 999.0},
+		{"1.5 < 2", true},
+		{"2 == 2.0", true},
+		{"0.1 != 0.1", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestEvalBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true", true},
+		{"false", false},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 < 1", false},
+		{"1 > 1", false},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"1 == 2", false},
+		{"1 != 2", true},
+		{"true == true", true},
+		{"false == false", true},
+		{"true == false", false},
+		{"true != false", true},
+		{"false != true", true},
+		{"(1 < 2) == true", true},
+		{"(1 < 2) == false", false},
+		{"(1 > 2) == true", false},
+		{"(1 > 2) == false", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 >= 1", true},
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"1 && 0", true},
+		{"!true || 5", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestBangOperator(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"!true", false},
+		{"!false", true},
+		{"!5", false},
+		{"!!true", true},
+		{"!!false", false},
+		{"!!5", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestIfElseExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"if (true) { 10 }", 10},
+		{"if (false) { 10 }", nil},
+		{"if (1) { 10 }", 10},
+		{"if (1 < 2) { 10 }", 10},
+		{"if (1 > 2) { 10 }", nil},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
+		{"let x = 3; if (x == 1) { 1 } else if (x == 2) { 2 } else if (x == 3) { 3 }", 3},
+		{"if (true) { 10 } else if (undefined) { 20 }", 10},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestReturnStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"return 10;", 10},
+		{"return 10; 9;", 10},
+		{"return 2 * 5; 9;", 10},
+		{"9; return 2 * 5; 9;", 10},
+		{`
+if (10 > 1) {
+	if (10 > 1) {
+		return 10;
+	}
+
+	return 1;
+}`, 10},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"5 + true;", "type mismatch: INTEGER + BOOLEAN"},
+		{"5 + true; 5;", "type mismatch: INTEGER + BOOLEAN"},
+		{"-true", "unknown operator: -BOOLEAN"},
+		{"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"5; true + false; 5", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"if (10 > 1) { true + false; }", "unknown operator: BOOLEAN + BOOLEAN"},
+		{`
+if (10 > 1) {
+	if (10 > 1) {
+		return true + false;
+	}
+
+	return 1;
+}`, "unknown operator: BOOLEAN + BOOLEAN"},
+		{"foobar", "identifier not found: foobar"},
+		{`"Hello" - "World"`, "unknown operator: STRING - STRING"},
+		{"10 / 0", "division by zero: 10 / 0"},
+		{"1.5 / 0", "division by zero: 1.5 / 0"},
+		{"10 % 0", "division by zero: 10 % 0"},
+		{"true && foobar", "identifier not found: foobar"},
+		{"1.5 + true", "type mismatch: FLOAT + BOOLEAN"},
+		{"let x = 5; x(1)", "not a function: INTEGER"},
+		{"let f = fn(x) { x }; f(1, 2)", "wrong number of arguments: want=1, got=2"},
+		{`"abc"[0]`, "index operator not supported: STRING[INTEGER]"},
+		{`[1, 2]["a"]`, "index operator not supported: ARRAY[STRING]"},
+		{"[1, foobar]", "identifier not found: foobar"},
+		{"[1, 2][foobar]", "identifier not found: foobar"},
+		{`{"name": "Monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
+		{`{fn(x) { x }: 1}`, "unusable as hash key: FUNCTION"},
+		{`{[1]: 1}`, "unusable as hash key: ARRAY"},
+		{`{"a": foobar}`, "identifier not found: foobar"},
+		{"if (false) { 1 } else if (foobar) { 2 }", "identifier not found: foobar"},
+		{"for x in 5 { x }", "cannot iterate over INTEGER"},
+		{"for x in foobar { x }", "identifier not found: foobar"},
+		{"while (foobar) { 1 }", "identifier not found: foobar"},
+		{"for x in [1] { foobar }", "identifier not found: foobar"},
+		{"for x in [1, 2] { x }; x", "identifier not found: x"},
+		{"x = 1", "cannot assign to undeclared identifier: x"},
+		{"let f = fn() { y = 1 }; f()", "cannot assign to undeclared identifier: y"},
+		{"let x = 1; x += true", "type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 1; x = foobar", "identifier not found: foobar"},
+		{"let a = [1]; a[1] = 2", "index out of range: 1 with length 1"},
+		{`let a = [1]; a["x"] = 2`, "index assignment not supported: ARRAY[STRING]"},
+		{`let h = {}; h[fn(x) { x }] = 1`, "unusable as hash key: FUNCTION"},
+		{`let s = "abc"; s[0] = "x"`, "index assignment not supported: STRING[INTEGER]"},
+		{"let x = 10; x /= 0", "division by zero: 10 / 0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf(" no error object returned got %T (%+v) ", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf(" wrong error message expected %q got %q ", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a;", 5},
+		{"let a = 5 * 5; a;", 25},
+		{"let a = 5; let b = a; b;", 5},
+		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestFunctionObject(t *testing.T) {
+	input := "fn(x) { x + 2; };"
+
+	evaluated := testEval(input)
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf(" object is not Function got %T (%+v) ", evaluated, evaluated)
+	}
+
+	if len(fn.Parameters) != 1 {
+		t.Fatalf(" function has wrong parameters %+v ", fn.Parameters)
+	}
+
+	if fn.Parameters[0].String() != "x" {
+		t.Fatalf(" parameter is not 'x' got %q ", fn.Parameters[0])
+	}
+
+	expectedBody := "{ (x + 2) }"
+	if fn.Body.String() != expectedBody {
+		t.Fatalf(" body is not %q got %q ", expectedBody, fn.Body.String())
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let identity = fn(x) { return x; }; identity(5);", 5},
+		{"let double = fn(x) { x * 2; }; double(5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"fn(x) { x; }(5)", 5},
+		{"let f = fn() { return 1; 2 }; f() + 10", 11},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestEmptyBlocks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn() {}; f()", nil},
+		{"let f = fn() { let x = 1; }; f()", nil},
+		{"if (true) {}", nil},
+		{"if (false) { 1 } else {}", nil},
+		{"if (false) { 1 } else if (true) {}", nil},
+		{"let f = fn() {}; let z = f(); z", nil},
+		{"let f = fn() {}; f() == if (true) {}", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if b, ok := tt.expected.(bool); ok {
+			testBooleanObject(t, evaluated, b)
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestEmptyBlocksInInfixExpressions(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"let f = fn() {}; f() + 1", "type mismatch: NULL + INTEGER"},
+		{"if (true) {} + 1", "type mismatch: NULL + INTEGER"},
+		{"let f = fn() { let x = 1; }; -f()", "unknown operator: -NULL"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf(" %q gave %T (%+v) expected an error ", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf(" wrong error message. expected=%q, got=%q ", tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	input := `
+let newAdder = fn(x) {
+	fn(y) { x + y };
+};
+
+let addTwo = newAdder(2);
+addTwo(2);`
+
+	testIntegerObject(t, testEval(input), 4)
+}
+
+func TestRecursiveFunction(t *testing.T) {
+	input := `
+let fib = fn(n) {
+	if (n < 2) { return n; }
+	fib(n - 1) + fib(n - 2)
+};
+fib(10);`
+
+	testIntegerObject(t, testEval(input), 55)
+}
+
+func TestStringLiteral(t *testing.T) {
+	input := `"Hello\tWorld!\u{1F600}"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf(" object is not String got %T (%+v) ", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello\tWorld!\U0001F600" {
+		t.Errorf(" string has wrong value %q ", str.Value)
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+	input := `"Hello" + " " + "World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf(" object is not String got %T (%+v) ", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf(" string has wrong value %q ", str.Value)
+	}
+}
+
+func TestStringComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" == "a"`, true},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`let x = "mon"; x + "key" == "monkey"`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestLogicalOperatorsShortCircuit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		// the right hand side would be an error if it was evaluated
+		{"false && undefined", false},
+		{"true || undefined", true},
+		{"false && 1 / 0", false},
+		{"let calls = fn() { fail() }; 1 > 2 && calls()", false},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf(" object is not Array got %T (%+v) ", evaluated, evaluated)
+	}
+
+	if len(result.Elements) != 3 {
+		t.Fatalf(" array has wrong number of elements got %d ", len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"let myArray = [1, 2, 3]; myArray[2];", 3},
+		{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+		{"let myArray = [1, 2, 3]; let i = myArray[0]; myArray[i]", 2},
+		{"[[1, 2], [3, 4]][1][0]", 3},
+		{"let f = fn() { [fn(x) { x * 2 }] }; f()[0](21)", 42},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", nil},
+		{"[][0]", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+	{
+		"one": 10 - 9,
+		two: 1 + 1,
+		"thr" + "ee": 6 / 2,
+		4: 4,
+		true: 5,
+		false: 6
+	}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf(" object is not Hash got %T (%+v) ", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                             5,
+		FALSE.HashKey():                            6,
+	}
+
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf(" hash has wrong number of pairs got %d ", len(result.Pairs))
+	}
+
+	for expectedKey, expectedValue := range expected {
+		pair, ok := result.Pairs[expectedKey]
+		if !ok {
+			t.Errorf(" no pair for given key in pairs ")
+			continue
+		}
+
+		testIntegerObject(t, pair.Value, expectedValue)
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`{1: 1, 1: 2}[1]`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"while (false) { 1 }", nil},
+		{"while (true) { break; }", nil},
+		{"for x in [] { x }", nil},
+		{"for x in [1, 2, 3] { x }", nil},
+		{"for x in [1, 2, 3] { if (x == 2) { return x * 10; } }", 20},
+		{"let f = fn() { for x in [1, 2, 3] { if (x < 3) { continue; } return x; } }; f()", 3},
+		{"let f = fn() { while (true) { break; } 5 }; f()", 5},
+		{"let f = fn() { for x in [1, 2] { for y in [3, 4] { break; } return x; } }; f()", 1},
+		{"let f = fn() { for x in [1, 2] { while (true) { if (x == 2) { return x; } break; } } }; f()", 2},
+		{"let x = 7; for x in [1] { x }; x", 7},
+		{"let fs = [0, 1]; let g = fn() { for i in fs { return fn() { i } } }; g()()", 0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestLoopScope(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 1) { let w = 5; i += 1 }; w", "identifier not found: w"},
+		{"for x in [1] { let z = 5 }; z", "identifier not found: z"},
+		{"let i = 0; let sum = 0; while (i < 3) { let d = i * 2; sum += d; i += 1 }; sum", 6},
+		{"let i = 0; while (i < 2) { let i = 10; break }; i", 0},
+		{"let fs = []; let i = 0; while (i < 2) { let j = i; fs = [fn() { j }]; i += 1 }; fs[0]()", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf(" %q gave %T (%+v) expected an error ", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf(" wrong error message. expected=%q, got=%q ", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestLoopControlInExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (true) { i += 1; let x = if (i > 3) { break }; }; i", 4},
+		{`let i = 0; while (true) { i += 1; let h = {"k": if (i > 3) { break }}; }; i`, 4},
+		{"let i = 0; while (true) { i += 1; let a = [if (i > 2) { break }]; }; i", 3},
+		{"let n = 0; for x in [1, 2, 3] { let y = if (x == 2) { continue }; n += x }; n", 4},
+		{"let n = 0; for x in [1, 2, 3] { n += if (x == 2) { continue } else { x } }; n", 4},
+		{"let f = fn() { let x = if (true) { return 5 }; 10 }; f()", 5},
+		{"let id = fn(x) { x }; let f = fn() { id(if (true) { return 6 }); 10 }; f()", 6},
+		{"let f = fn() { -if (true) { return 7 } }; f()", 7},
+		{"let i = 0; while (true) { i += 1; if (i > 2) { break } else { 0 } + 1 }; i", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForIterables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`for c in "héllo" { if (c != "h") { return c } }`, "é"},
+		{`for k in {"b": 2, "a": 1} { return k }`, "a"},
+		{`for s in ["x", "y"] { return s }`, "x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf(" object is not String got %T (%+v) ", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf(" %q gave %q expected %q ", tt.input, str.Value, tt.expected)
+		}
+	}
+}
+
+func TestForHashKeyOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{9: 0, 10: 0, -1: 0, 100: 0}`, "[-1, 9, 10, 100, 0, 0]"},
+		{`{"b": 0, "a": 0, "ab": 0}`, "[a, ab, b, 0, 0, 0]"},
+		{`{"2": 0, 10: 0, true: 0, 1: 0, false: 0, "1": 0}`, "[false, true, 1, 10, 1, 2]"},
+	}
+
+	for _, tt := range tests {
+		// unused slots stay 0
+		input := "let keys = [0, 0, 0, 0, 0, 0]; let i = 0; for k in " + tt.input +
+			" { keys[i] = k; i += 1 }; keys"
+		evaluated := testEval(input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf(" for over %s visited %s expected %s ", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = 2", 2},
+		{"let x = 1; let y = 1; x = y = 5; x + y", 10},
+		{"let x = 10; x += 5; x", 15},
+		{"let x = 10; x -= 5; x", 5},
+		{"let x = 10; x *= 5; x", 50},
+		{"let x = 10; x /= 5; x", 2},
+		{"let x = 10; x %= 4; x", 2},
+		{"let i = 0; while (i < 5) { i += 1 }; i", 5},
+		{"let sum = 0; for x in [1, 2, 3] { sum += x }; sum", 6},
+		{"let count = 0; let inc = fn() { count += 1 }; inc(); inc(); count", 2},
+		{"let x = 1; let f = fn() { let x = 5; x = 10; x }; f() + x", 11},
+		{"let a = [1, 2, 3]; a[1] = 20; a[1]", 20},
+		{"let a = [1, 2, 3]; a[2] *= 3; a[2]", 9},
+		{"let a = [1]; let b = a; b[0] = 7; a[0]", 7},
+		{`let h = {"a": 1}; h["a"] = 2; h["a"]`, 2},
+		{`let h = {}; h["b"] = 3; h["b"]`, 3},
+		{`let h = {"a": 1}; h["a"] += 4; h["a"]`, 5},
+		{"let m = [[1, 2], [3, 4]]; m[1][0] = 30; m[1][0]", 30},
+		{"let i = 0; let a = [0, 0]; a[i] = i = 1; a[0] + i", 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
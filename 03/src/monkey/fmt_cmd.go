@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"monkey/format"
+	"os"
+)
+
+// fmtCommand implements monkey fmt [-l] [-w] [file ...]. Without files it
+// formats standard input to standard output.
+func fmtCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	list := flags.Bool("l", false, "list files whose formatting differs")
+	write := flags.Bool("w", false, "write the result back to the file instead of printing it")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: monkey fmt [-l] [-w] [file ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintf(os.Stderr, "monkey fmt: cannot use -w with standard input\n")
+			return 2
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey fmt: %s\n", err)
+			return 1
+		}
+		return formatFile("<stdin>", src, *list, false)
+	}
+
+	status := 0
+	for _, filename := range flags.Args() {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey fmt: %s\n", err)
+			status = 1
+			continue
+		}
+		if s := formatFile(filename, src, *list, *write); s != 0 {
+			status = s
+		}
+	}
+	return status
+}
+
+func formatFile(filename string, src []byte, list bool, write bool) int {
+	out, err := format.Source(filename, src)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	changed := !bytes.Equal(src, out)
+	if list && changed {
+		fmt.Println(filename)
+	}
+
+	if write {
+		if !changed {
+			return 0
+		}
+		info, err := os.Stat(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey fmt: %s\n", err)
+			return 1
+		}
+		if err := ioutil.WriteFile(filename, out, info.Mode().Perm()); err != nil {
+			fmt.Fprintf(os.Stderr, "monkey fmt: %s\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if !list {
+		os.Stdout.Write(out)
+	}
+	return 0
+}
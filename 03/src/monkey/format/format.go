@@ -0,0 +1,610 @@
+// Package format pretty-prints monkey programs in one canonical layout.
+// Statements go on their own line, blocks are indented with tabs and
+// parentheses are only written where the precedence of the operators
+// requires them. Comments and single blank lines between statements are
+// kept, so formatting an already formatted program changes nothing.
+package format
+
+import (
+	"bytes"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"strconv"
+	"strings"
+)
+
+// ParseError is returned by Source when the input does not parse, a
+// program with errors is never formatted.
+type ParseError struct {
+	Diagnostics []parser.Diagnostic
+}
+
+func (e *ParseError) Error() string {
+	return strings.Join(parser.Messages(e.Diagnostics), "\n")
+}
+
+// Source formats the monkey program in src. The filename is only used in
+// error messages.
+func Source(filename string, src []byte) ([]byte, error) {
+	p := parser.New(lexer.NewFile(filename, string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Diagnostics: p.Errors()}
+	}
+
+	pr := &printer{comments: scanComments(filename, src)}
+	pr.program(program)
+	return pr.buf.Bytes(), nil
+}
+
+// Node formats a single node without comments. Statements are written
+// the same way Source writes them, the output of a program ends with a
+// newline.
+func Node(node ast.Node) string {
+	pr := &printer{}
+	switch node := node.(type) {
+	case *ast.Program:
+		pr.program(node)
+	case ast.Statement:
+		pr.statement(node)
+	case ast.Expression:
+		pr.expression(node, LOWEST)
+	}
+	return pr.buf.String()
+}
+
+// scanComments lexes src a second time and keeps only the comments, the
+// parser never sees them
+func scanComments(filename string, src []byte) []token.Token {
+	l := lexer.NewFile(filename, string(src))
+	l.SetMode(lexer.ScanComments)
+
+	comments := []token.Token{}
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.EOF {
+			return comments
+		}
+		if tok.Type == token.COMMENT {
+			comments = append(comments, tok)
+		}
+	}
+}
+
+// the binding strength of the expressions, mirrors the precedences of the
+// parser
+const (
+	_ int = iota
+	LOWEST
+	ASSIGN
+	LOGICAL_OR
+	LOGICAL_AND
+	EQUALS
+	LESSGREATER
+	SUM
+	PRODUCT
+	PREFIX
+	CALL
+	PRIMARY
+)
+
+var precedences = map[string]int{
+	"||": LOGICAL_OR,
+	"&&": LOGICAL_AND,
+	"==": EQUALS,
+	"!=": EQUALS,
+	"<":  LESSGREATER,
+	">":  LESSGREATER,
+	"<=": LESSGREATER,
+	">=": LESSGREATER,
+	"+":  SUM,
+	"-":  SUM,
+	"*":  PRODUCT,
+	"/":  PRODUCT,
+	"%":  PRODUCT,
+}
+
+func precedenceOf(e ast.Expression) int {
+	switch e := e.(type) {
+	case *ast.AssignExpression:
+		return ASSIGN
+	case *ast.InfixExpression:
+		if p, ok := precedences[e.Operator]; ok {
+			return p
+		}
+		return LOWEST
+	case *ast.PrefixExpression:
+		return PREFIX
+	case *ast.CallExpression, *ast.IndexExpression:
+		return CALL
+	default:
+		return PRIMARY
+	}
+}
+
+type printer struct {
+	buf    bytes.Buffer
+	indent int
+
+	// comments not written yet, in source order
+	comments []token.Token
+	// lastLine is the source line the last statement or comment ended
+	// on, 0 at the start of a block where no blank line is wanted
+	lastLine int
+}
+
+func (p *printer) write(s string) {
+	p.buf.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.buf.WriteByte('\n')
+	p.buf.WriteString(strings.Repeat("\t", p.indent))
+}
+
+func (p *printer) program(program *ast.Program) {
+	p.statements(program.Statements, -1)
+	if p.buf.Len() > 0 {
+		p.write("\n")
+	}
+}
+
+// statements writes list, one statement per line, together with the
+// comments in front of them. end is the offset of the } closing the
+// block, comments before it belong into the block. It is -1 for the
+// program, which takes all remaining comments.
+func (p *printer) statements(list []ast.Statement, end int) {
+	first := true
+	for i, stmt := range list {
+		p.commentsBefore(stmt.Pos(), &first)
+		p.separate(stmt.Pos().Line, &first)
+		p.statement(stmt)
+
+		if i+1 < len(list) && needsSemicolon(stmt, list[i+1]) {
+			p.write(";")
+		}
+
+		next := end
+		if i+1 < len(list) {
+			next = list[i+1].Pos().Offset
+		}
+		p.trailingComment(stmt.End().Line, next)
+		p.lastLine = stmt.End().Line
+	}
+
+	for len(p.comments) > 0 && (end < 0 || p.comments[0].Pos.Offset < end) {
+		p.comment(&first)
+	}
+}
+
+// commentsBefore writes the comments that start before pos on lines of
+// their own
+func (p *printer) commentsBefore(pos token.Position, first *bool) {
+	for len(p.comments) > 0 && pos.IsValid() && p.comments[0].Pos.Offset < pos.Offset {
+		p.comment(first)
+	}
+}
+
+func (p *printer) comment(first *bool) {
+	c := p.comments[0]
+	p.comments = p.comments[1:]
+
+	p.separate(c.Pos.Line, first)
+	p.write(c.Literal)
+	p.lastLine = c.End.Line
+}
+
+// trailingComment keeps a comment on the line of the statement it follows
+// as long as it comes before the next statement
+func (p *printer) trailingComment(line int, next int) {
+	if next < 0 {
+		next = int(^uint(0) >> 1)
+	}
+	if line > 0 {
+		p.lineComment(line, next)
+	}
+}
+
+// separate starts a new line for a statement or comment on the given
+// source line, keeping one blank line if there was at least one in the
+// source
+func (p *printer) separate(line int, first *bool) {
+	if *first {
+		*first = false
+		if p.buf.Len() > 0 {
+			p.newline()
+		}
+		p.lastLine = line
+		return
+	}
+
+	if p.lastLine > 0 && line > p.lastLine+1 {
+		p.write("\n")
+	}
+	p.newline()
+}
+
+// the ; after an expression statement that ends in a } is left out unless
+// the next statement would otherwise continue the expression
+func needsSemicolon(stmt ast.Statement, next ast.Statement) bool {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok || !endsWithBlock(es.Expression) {
+		return false
+	}
+
+	switch Node(next)[0] {
+	case '(', '[', '-':
+		return true
+	default:
+		return false
+	}
+}
+
+func endsWithBlock(e ast.Expression) bool {
+	switch e.(type) {
+	case *ast.IfExpression, *ast.FunctionLiteral:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.write("let " + stmt.Name.Value + " = ")
+		p.expression(stmt.Value, LOWEST)
+		p.write(";")
+
+	case *ast.ReturnStatement:
+		p.write("return")
+		if stmt.ReturnValue != nil {
+			p.write(" ")
+			p.expression(stmt.ReturnValue, LOWEST)
+		}
+		p.write(";")
+
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression, LOWEST)
+		if !endsWithBlock(stmt.Expression) {
+			p.write(";")
+		}
+
+	case *ast.WhileStatement:
+		p.write("while (")
+		p.expression(stmt.Condition, LOWEST)
+		p.write(") ")
+		p.block(stmt.Body)
+
+	case *ast.ForStatement:
+		p.write("for " + stmt.Variable.Value + " in ")
+		p.expression(stmt.Iterable, LOWEST)
+		p.write(" ")
+		p.block(stmt.Body)
+
+	case *ast.BreakStatement:
+		p.write("break;")
+
+	case *ast.ContinueStatement:
+		p.write("continue;")
+
+	case *ast.BlockStatement:
+		p.block(stmt)
+	}
+}
+
+func (p *printer) block(block *ast.BlockStatement) {
+	p.inlineComments(block.Token.Pos, true)
+
+	// a block that was not parsed from source has no comments
+	end := 0
+	if block.Rbrace.Pos.IsValid() {
+		end = block.Rbrace.Pos.Offset
+	}
+
+	if len(block.Statements) == 0 && !p.hasCommentBefore(end) {
+		p.write("{}")
+		return
+	}
+
+	p.write("{")
+	p.indent += 1
+	lastLine := p.lastLine
+	p.lastLine = 0
+	p.statements(block.Statements, end)
+	p.indent -= 1
+	p.lastLine = lastLine
+	p.newline()
+	p.write("}")
+}
+
+func (p *printer) hasCommentBefore(end int) bool {
+	return len(p.comments) > 0 && p.comments[0].Pos.Offset < end
+}
+
+// inlineComments writes the comments in front of pos where the printer is
+// inside a statement. A block comment stays on the line, after a line
+// comment the code continues on the next line, indented once more when it
+// continues an expression.
+func (p *printer) inlineComments(pos token.Position, continued bool) {
+	for len(p.comments) > 0 && pos.IsValid() && p.comments[0].Pos.Offset < pos.Offset {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		p.space()
+		p.write(c.Literal)
+		if !isLineComment(c) {
+			p.write(" ")
+			continue
+		}
+		p.newline()
+		if continued {
+			p.write("\t")
+		}
+	}
+}
+
+func isLineComment(c token.Token) bool {
+	return strings.HasPrefix(c.Literal, "//")
+}
+
+// space separates what comes next from the output so far, unless that
+// already ends in a space, an opening bracket or the indentation of a line
+func (p *printer) space() {
+	out := p.buf.Bytes()
+	if len(out) == 0 {
+		return
+	}
+	switch out[len(out)-1] {
+	case ' ', '\t', '\n', '(', '[', '{':
+	default:
+		p.write(" ")
+	}
+}
+
+// listItem is an element of a bracketed list, with its extent in the
+// source and how to print it
+type listItem struct {
+	pos, end token.Position
+	write    func()
+}
+
+// list writes items separated by commas between open and close, which
+// are the positions of the brackets in the source. The items stay on one
+// line unless there was a line break or a comment between the brackets
+// and the items in the source, then every item gets a line of its own and
+// keeps the comment that followed it on its line.
+func (p *printer) list(open, close string, openPos, closePos token.Position, items []listItem) {
+	p.inlineComments(openPos, true)
+	p.write(open)
+
+	if !p.multiline(openPos, closePos, items) {
+		for i, item := range items {
+			if i > 0 {
+				p.write(", ")
+			}
+			item.write()
+		}
+		p.write(close)
+		return
+	}
+
+	p.indent += 1
+	for i, item := range items {
+		p.ownLineComments(item.pos.Offset)
+		p.newline()
+		item.write()
+
+		next := closePos.Offset
+		if i+1 < len(items) {
+			p.write(",")
+			next = items[i+1].pos.Offset
+		}
+		p.lineComment(item.end.Line, next)
+	}
+	p.ownLineComments(closePos.Offset)
+	p.indent -= 1
+	p.newline()
+	p.write(close)
+}
+
+// multiline reports whether a list spread over several lines or had
+// comments outside of its items, an empty list only for comments
+func (p *printer) multiline(openPos, closePos token.Position, items []listItem) bool {
+	if !openPos.IsValid() || !closePos.IsValid() {
+		return false
+	}
+	if len(items) == 0 {
+		return p.hasCommentBetween(openPos.Offset, closePos.Offset)
+	}
+
+	start := openPos
+	for _, item := range append(items, listItem{pos: closePos}) {
+		if item.pos.Line != start.Line || p.hasCommentBetween(start.Offset, item.pos.Offset) {
+			return true
+		}
+		start = item.end
+	}
+	return false
+}
+
+func (p *printer) hasCommentBetween(start, end int) bool {
+	for _, c := range p.comments {
+		if c.Pos.Offset >= end {
+			return false
+		}
+		if c.Pos.Offset >= start {
+			return true
+		}
+	}
+	return false
+}
+
+// ownLineComments writes the comments before end each on a line of its own
+func (p *printer) ownLineComments(end int) {
+	for len(p.comments) > 0 && p.comments[0].Pos.Offset < end {
+		p.newline()
+		p.write(p.comments[0].Literal)
+		p.comments = p.comments[1:]
+	}
+}
+
+// lineComment writes a comment that starts on line before next at the
+// end of the current line
+func (p *printer) lineComment(line int, next int) {
+	if len(p.comments) == 0 {
+		return
+	}
+	c := p.comments[0]
+	if c.Pos.Line != line || c.Pos.Offset >= next || strings.Contains(c.Literal, "\n") {
+		return
+	}
+	p.comments = p.comments[1:]
+	p.write(" " + c.Literal)
+}
+
+// expression writes e, in parentheses if it binds less tightly than
+// precedence
+func (p *printer) expression(e ast.Expression, precedence int) {
+	p.inlineComments(e.Pos(), true)
+
+	if precedenceOf(e) < precedence {
+		p.write("(")
+		p.expression(e, LOWEST)
+		p.write(")")
+		return
+	}
+
+	switch e := e.(type) {
+	case *ast.Identifier:
+		p.write(e.Value)
+
+	case *ast.IntegerLiteral:
+		if e.Token.Literal != "" {
+			p.write(e.Token.Literal)
+		} else {
+			p.write(strconv.FormatInt(e.Value, 10))
+		}
+
+	case *ast.FloatLiteral:
+		if e.Token.Literal != "" {
+			p.write(e.Token.Literal)
+		} else {
+			p.write(formatFloat(e.Value))
+		}
+
+	case *ast.StringLiteral:
+		p.write(e.String())
+
+	case *ast.Boolean:
+		p.write(strconv.FormatBool(e.Value))
+
+	case *ast.PrefixExpression:
+		p.write(e.Operator)
+		// -(-x) written as --x would read like a decrement
+		right, ok := e.Right.(*ast.PrefixExpression)
+		if ok && e.Operator == "-" && right.Operator == "-" {
+			p.write("(")
+			p.expression(e.Right, LOWEST)
+			p.write(")")
+			break
+		}
+		p.expression(e.Right, PREFIX)
+
+	case *ast.InfixExpression:
+		prec := precedenceOf(e)
+		p.expression(e.Left, prec)
+		p.write(" " + e.Operator + " ")
+		p.expression(e.Right, prec+1)
+
+	case *ast.AssignExpression:
+		p.expression(e.Target, CALL)
+		p.write(" " + e.Operator + " ")
+		p.expression(e.Value, LOWEST)
+
+	case *ast.IfExpression:
+		p.write("if (")
+		p.expression(e.Condition, LOWEST)
+		p.write(") ")
+		p.block(e.Consequence)
+		for _, ei := range e.ElseIfs {
+			p.inlineComments(ei.Token.Pos, false)
+			p.space()
+			p.write("else if (")
+			p.expression(ei.Condition, LOWEST)
+			p.write(") ")
+			p.block(ei.Consequence)
+		}
+		if e.Alternative != nil {
+			// there is no else token, a comment in front of the block
+			// is written in front of the else
+			p.inlineComments(e.Alternative.Token.Pos, false)
+			p.space()
+			p.write("else ")
+			p.block(e.Alternative)
+		}
+
+	case *ast.FunctionLiteral:
+		items := []listItem{}
+		for _, param := range e.Parameters {
+			param := param
+			items = append(items, listItem{param.Pos(), param.End(), func() {
+				p.inlineComments(param.Pos(), true)
+				p.write(param.Value)
+			}})
+		}
+		// the ( is not kept, the parameters start after the fn
+		p.write("fn")
+		p.list("(", ")", e.Token.End, e.Body.Token.Pos, items)
+		p.write(" ")
+		p.block(e.Body)
+
+	case *ast.CallExpression:
+		p.expression(e.Function, CALL)
+		p.list("(", ")", e.Token.Pos, e.Rparen.Pos, p.expressionItems(e.Arguments))
+
+	case *ast.ArrayLiteral:
+		p.list("[", "]", e.Token.Pos, e.Rbracket.Pos, p.expressionItems(e.Elements))
+
+	case *ast.IndexExpression:
+		p.expression(e.Left, CALL)
+		p.write("[")
+		p.expression(e.Index, LOWEST)
+		p.inlineComments(e.Rbracket.Pos, true)
+		p.buf.Truncate(len(bytes.TrimRight(p.buf.Bytes(), " ")))
+		p.write("]")
+
+	case *ast.HashLiteral:
+		items := []listItem{}
+		for _, pair := range e.Pairs {
+			pair := pair
+			items = append(items, listItem{pair.Key.Pos(), pair.Value.End(), func() {
+				p.expression(pair.Key, LOWEST)
+				p.write(": ")
+				p.expression(pair.Value, LOWEST)
+			}})
+		}
+		p.list("{", "}", e.Token.Pos, e.Rbrace.Pos, items)
+	}
+}
+
+func (p *printer) expressionItems(list []ast.Expression) []listItem {
+	items := []listItem{}
+	for _, e := range list {
+		e := e
+		items = append(items, listItem{e.Pos(), e.End(), func() { p.expression(e, LOWEST) }})
+	}
+	return items
+}
+
+// formatFloat writes a float that was not read from source so that it
+// still lexes as a FLOAT
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
@@ -0,0 +1,217 @@
+package format
+
+import (
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=5", "let x = 5;\n"},
+		{"let   add = fn(a,b){a+b};", "let add = fn(a, b) {\n\ta + b;\n};\n"},
+		{"return x*2", "return x * 2;\n"},
+		{"a + b * c; (a + b) * c", "a + b * c;\n(a + b) * c;\n"},
+		{"a - (b - c); (a - b) - c", "a - (b - c);\na - b - c;\n"},
+		{"-(a + b); !(!a); -f(x)", "-(a + b);\n!!a;\n-f(x);\n"},
+		{"-(-1); - -a; -(!a); !(-a); a - -b", "-(-1);\n-(-a);\n-!a;\n!-a;\na - -b;\n"},
+		{"(a && b) || c; a && (b || c)", "a && b || c;\na && (b || c);\n"},
+		{"x = y = 1; (x = 1) + 2; a[0] += 1", "x = y = 1;\n(x = 1) + 2;\na[0] += 1;\n"},
+		{"(a + b)[0]; (-a)(1); f(x)[0](y)", "(a + b)[0];\n(-a)(1);\nf(x)[0](y);\n"},
+		{`[1,2 ,3]; {"a":1,2:[]}; {}`, "[1, 2, 3];\n{\"a\": 1, 2: []};\n{};\n"},
+		{"0xff + 1_000 + 1.5e3", "0xff + 1_000 + 1.5e3;\n"},
+		{`"a\tb\"c"`, "\"a\\tb\\\"c\";\n"},
+		{
+			"if (x) { 1 } else if (y) { 2 } else { 3 }",
+			"if (x) {\n\t1;\n} else if (y) {\n\t2;\n} else {\n\t3;\n}\n",
+		},
+		{"if (x) {}", "if (x) {}\n"},
+		{
+			"while (i < 3) { i += 1; if (i == 2) { break } }",
+			"while (i < 3) {\n\ti += 1;\n\tif (i == 2) {\n\t\tbreak;\n\t}\n}\n",
+		},
+		{"for x in [1, 2] { continue; }", "for x in [1, 2] {\n\tcontinue;\n}\n"},
+		{
+			"let f = fn() { fn(x) { x } };",
+			"let f = fn() {\n\tfn(x) {\n\t\tx;\n\t}\n};\n",
+		},
+		// the ; keeps the next statement from being parsed as an operand
+		{"if (x) { 1 }; -1", "if (x) {\n\t1;\n};\n-1;\n"},
+		{"if (x) { 1 }; (a + b) * c", "if (x) {\n\t1;\n};\n(a + b) * c;\n"},
+		{"if (x) { 1 }; (y)", "if (x) {\n\t1;\n}\ny;\n"},
+		{"if (x) { 1 }; [1]", "if (x) {\n\t1;\n};\n[1];\n"},
+		{"if (x) { 1 } y", "if (x) {\n\t1;\n}\ny;\n"},
+		{"fn(x) { x }(1)", "fn(x) {\n\tx;\n}(1);\n"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		out, err := Source("", []byte(tt.input))
+		if err != nil {
+			t.Errorf(" input %q gave error %s ", tt.input, err)
+			continue
+		}
+
+		if string(out) != tt.expected {
+			t.Errorf(" input %q\n got      %q\n expected %q ", tt.input, out, tt.expected)
+		}
+	}
+}
+
+func TestSourceComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"// hello\nlet x = 1;", "// hello\nlet x = 1;\n"},
+		{"let x = 1; // one\nlet y = 2; /* two */", "let x = 1; // one\nlet y = 2; /* two */\n"},
+		{"let x = 1;\n\n\n// after\n", "let x = 1;\n\n// after\n"},
+		{"// only a comment", "// only a comment\n"},
+		{
+			"let f = fn() {\n  // inside\n  x; // trailing\n  // at the end\n};",
+			"let f = fn() {\n\t// inside\n\tx; // trailing\n\t// at the end\n};\n",
+		},
+		{"if (x) { /* empty */ }", "if (x) {\n\t/* empty */\n}\n"},
+		{"if (x) { 1 } // after then\ny", "if (x) {\n\t1;\n} // after then\ny;\n"},
+		{"if (x) {\n  1 // one\n} else {\n  2 // two\n}", "if (x) {\n\t1; // one\n} else {\n\t2; // two\n}\n"},
+		{"/* a\n   b */\nx", "/* a\n   b */\nx;\n"},
+		{"a; b; // belongs to b", "a;\nb; // belongs to b\n"},
+	}
+
+	for _, tt := range tests {
+		out, err := Source("", []byte(tt.input))
+		if err != nil {
+			t.Errorf(" input %q gave error %s ", tt.input, err)
+			continue
+		}
+
+		if string(out) != tt.expected {
+			t.Errorf(" input %q\n got      %q\n expected %q ", tt.input, out, tt.expected)
+		}
+	}
+}
+
+func TestSourceCommentInsideExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let h = {\n \"a\": 1, // first\n \"b\": 2 // second\n};",
+			"let h = {\n\t\"a\": 1, // first\n\t\"b\": 2 // second\n};\n",
+		},
+		{
+			"let a = [\n  // leading\n  1,\n  2, /* two */\n  3\n  // after\n];",
+			"let a = [\n\t// leading\n\t1,\n\t2, /* two */\n\t3\n\t// after\n];\n",
+		},
+		{"f(1, // one\n  2)", "f(\n\t1, // one\n\t2\n);\n"},
+		{"f(a,\n  b)", "f(\n\ta,\n\tb\n);\n"},
+		{"f(fn() {\n  1\n})", "f(fn() {\n\t1;\n});\n"},
+		{
+			"let f = fn(a, /* b */ c) {\n  a + c;\n};",
+			"let f = fn(\n\ta, /* b */\n\tc\n) {\n\ta + c;\n};\n",
+		},
+		{"let f = fn(a, c) { /* b */ a };", "let f = fn(a, c) {\n\t/* b */\n\ta;\n};\n"},
+		{
+			"if (x) {\n  1\n} // after then\nelse {\n  2\n}",
+			"if (x) {\n\t1;\n} // after then\nelse {\n\t2;\n}\n",
+		},
+		{
+			"if (x) {\n  1\n} /* c */ else if (y) {\n  2\n}",
+			"if (x) {\n\t1;\n} /* c */ else if (y) {\n\t2;\n}\n",
+		},
+		{"let x = 1 + /* inline */ 2;", "let x = 1 + /* inline */ 2;\n"},
+		{"let x = 1 + // line\n  2;", "let x = 1 + // line\n\t2;\n"},
+		{"while (x) /* w */ { 1 }", "while (x) /* w */ {\n\t1;\n}\n"},
+		{"a[0 /* zero */]", "a[0 /* zero */];\n"},
+	}
+
+	for _, tt := range tests {
+		out, err := Source("", []byte(tt.input))
+		if err != nil {
+			t.Errorf(" input %q gave error %s ", tt.input, err)
+			continue
+		}
+		if string(out) != tt.expected {
+			t.Errorf(" input %q\n got      %q\n expected %q ", tt.input, out, tt.expected)
+			continue
+		}
+
+		again, err := Source("", out)
+		if err != nil || string(again) != string(out) {
+			t.Errorf(" formatting %q again gave %q (%v) ", out, again, err)
+		}
+		if parse(t, tt.input) != parse(t, string(out)) {
+			t.Errorf(" formatting changed the program\n before %s\n after  %s ", parse(t, tt.input), parse(t, string(out)))
+		}
+	}
+}
+
+func TestSourceBlankLines(t *testing.T) {
+	input := "let a = 1;\nlet b = 2;\n\n\n\nlet c = 3;\nfn() {\n\n  a;\n\n  b;\n}"
+	expected := "let a = 1;\nlet b = 2;\n\nlet c = 3;\nfn() {\n\ta;\n\n\tb;\n}\n"
+
+	out, err := Source("", []byte(input))
+	if err != nil {
+		t.Fatalf(" unexpected error %s ", err)
+	}
+
+	if string(out) != expected {
+		t.Errorf(" got %q expected %q ", out, expected)
+	}
+}
+
+func TestSourceIsIdempotentAndKeepsMeaning(t *testing.T) {
+	inputs := []string{
+		"let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; fib(10);",
+		"// counter\nlet i = 0;\nwhile (i < 10) { i += 1; // step\n}\n\n/* done */ i",
+		`let h = {"a": [1, 2], "b": fn(x) { x * -(1 + 2) }}; h["b"](h["a"][0])`,
+		"let x = !(a == b) != (c < d) && -(-e) || f[g](h) % (i = j);",
+		"if (a) { b } else if (c) { d };\n-x; for k in {1: 2} { if (k) { continue } }",
+	}
+
+	for _, input := range inputs {
+		once, err := Source("", []byte(input))
+		if err != nil {
+			t.Errorf(" input %q gave error %s ", input, err)
+			continue
+		}
+
+		twice, err := Source("", once)
+		if err != nil {
+			t.Errorf(" formatted %q does not parse: %s ", once, err)
+			continue
+		}
+
+		if string(once) != string(twice) {
+			t.Errorf(" formatting is not idempotent\n once  %q\n twice %q ", once, twice)
+		}
+
+		if parse(t, input) != parse(t, string(once)) {
+			t.Errorf(" formatting changed the program\n before %s\n after  %s ", parse(t, input), parse(t, string(once)))
+		}
+	}
+}
+
+func parse(t *testing.T, input string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf(" input %q has errors %q ", input, parser.Messages(p.Errors()))
+	}
+	return program.String()
+}
+
+func TestSourceParseError(t *testing.T) {
+	_, err := Source("test.mk", []byte("let x = ;"))
+	if err == nil {
+		t.Fatalf(" expected an error ")
+	}
+
+	if err.Error() != "test.mk:1:9: no prefix parse func for ;" {
+		t.Errorf(" wrong error %q ", err.Error())
+	}
+}
@@ -0,0 +1,67 @@
+package lexer
+
+import (
+	"monkey/token"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func FuzzNextToken(f *testing.F) {
+	seeds := []string{
+		"let five = 5;\nlet add = fn(x, y) {\n  x + y;\n};\nadd(five, 10);",
+		"!-/ *5;\n5 < 10 > 5;\nif (5 < 10) { return true; } else { return false; }",
+		"10 == 10; 10 != 9; a <= b >= c && d || e % f",
+		"x += 1; x -= 1; x *= 2; x /= 2; x %= 3; a[0] = {\"k\": 1}",
+		"while (x) { break; continue; } for x in y {}",
+		"let größe = 5;\nlet 名前 = x1 + π;\n€",
+		"a \xff b \xe2\x82",
+		`"a\nb\tc" "say \"hi\" \\o/" "\u{41}\u{e9}\u{1F600}" "two` + "\n" + `lines"`,
+		`"unterminated`,
+		`"bad \q escape" "\u0041" "\u{}" "\u{1234567}" "\u{D800}"`,
+		"0x1F_ff 0X_ab 0o755 0b1010_0101 3.14159 1e10 1.5e-3 6.02E+23",
+		"0x 0b102 0o8 1e 1__0 1_",
+		"// line\r\nx /* block /* nested */ */ y // end",
+		"let x = 1;\n/* outer /* inner */ never closed",
+		"a & b | c @",
+		"a\x00b",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		for _, mode := range []Mode{0, ScanComments} {
+			l := New(input)
+			l.SetMode(mode)
+
+			// every token but EOF consumes at least one byte
+			last := token.Position{}
+			for i := 0; ; i++ {
+				if i > len(input) {
+					t.Fatalf(" %q gives more tokens than bytes ", input)
+				}
+
+				tok := l.NextToken()
+				if tok.Pos.Offset < last.Offset || tok.End.Offset < tok.Pos.Offset {
+					t.Fatalf(" %q token %q has span %d-%d after %d ", input, tok.Literal, tok.Pos.Offset, tok.End.Offset, last.Offset)
+				}
+				last = tok.End
+
+				if tok.Type == token.EOF {
+					if tok.Pos.Offset != len(input) {
+						t.Fatalf(" %q ends at offset %d of %d ", input, tok.Pos.Offset, len(input))
+					}
+					break
+				}
+			}
+		}
+
+		expected := lexAll(New(input))
+		actual := lexAll(NewReader(iotest.OneByteReader(strings.NewReader(input))))
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf(" %q reader differs from string lexer\nexpected=%+v\ngot=     %+v", input, expected, actual)
+		}
+	})
+}
@@ -1,112 +1,563 @@
 package lexer
 
-import "monkey/token"
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"monkey/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Mode controls optional lexer behaviour.
+type Mode uint
+
+const (
+	// ScanComments makes NextToken return comments as COMMENT tokens
+	// instead of skipping them like whitespace.
+	ScanComments Mode = 1 << iota
+)
+
+// ErrorHandler is called for every malformed token with the position of
+// the problem and a message describing it.
+type ErrorHandler func(pos token.Position, msg string)
 
 type Lexer struct {
-	input        string
+	reader       *bufio.Reader
+	filename     string
 	position     int
 	readPosition int
-	ch           byte
+	ch           rune
+
+	// raw bytes of ch, invalid utf-8 is kept as is
+	chBytes [utf8.UTFMax]byte
+	chSize  int
+	eof     bool
+	readErr error
+
+	// lexeme collects the raw bytes of the token being read
+	lexeme []byte
+
+	// line and column of ch, the column counts characters and not bytes
+	line   int
+	column int
+
+	errorHandler ErrorHandler
+	mode         Mode
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// NewFile is like New but records filename in the position of every token.
+func NewFile(filename, input string) *Lexer {
+	return NewFileReader(filename, strings.NewReader(input))
+}
+
+// NewReader lexes the input as it is read from r instead of needing the
+// whole program in memory. It produces the same tokens as New.
+func NewReader(r io.Reader) *Lexer {
+	return NewFileReader("", r)
+}
+
+// NewFileReader is like NewReader but records filename in the position of
+// every token.
+func NewFileReader(filename string, r io.Reader) *Lexer {
+	l := &Lexer{reader: bufio.NewReader(r), filename: filename, line: 1}
 	l.readChar()
 	return l
 }
 
+// SetErrorHandler installs h to be told about malformed tokens such as
+// unterminated strings. Without a handler those only show up as ILLEGAL
+// tokens.
+func (l *Lexer) SetErrorHandler(h ErrorHandler) {
+	l.errorHandler = h
+}
+
+// SetMode changes the behaviour of the lexer for the following tokens.
+func (l *Lexer) SetMode(mode Mode) {
+	l.mode = mode
+}
+
+func (l *Lexer) error(pos token.Position, format string, a ...interface{}) {
+	if l.errorHandler != nil {
+		l.errorHandler(pos, fmt.Sprintf(format, a...))
+	}
+}
+
 func (l *Lexer) readChar() {
-	if l.readPosition >= len(l.input) {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
+
+	l.lexeme = append(l.lexeme, l.chBytes[:l.chSize]...)
+
+	b := l.peekBytes()
+	if len(b) == 0 {
 		l.ch = 0
+		l.chSize = 0
+		l.eof = true
 	} else {
-		l.ch = l.input[l.readPosition]
+		// invalid utf-8 decodes to utf8.RuneError with a size of 1, so
+		// we always make progress
+		l.ch, l.chSize = utf8.DecodeRune(b)
+		copy(l.chBytes[:], b[:l.chSize])
+		l.reader.Discard(l.chSize)
 	}
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += l.chSize
+}
+
+// peekBytes returns the encoding of the next character without consuming
+// it. It only waits for as many bytes as the first byte announces, so that
+// interactive input is not held up.
+func (l *Lexer) peekBytes() []byte {
+	b, err := l.reader.Peek(1)
+	if len(b) > 0 && b[0] >= utf8.RuneSelf {
+		n := 2
+		if b[0] >= 0xF0 {
+			n = 4
+		} else if b[0] >= 0xE0 {
+			n = 3
+		}
+		b, err = l.reader.Peek(n)
+	}
+
+	// a failed read ends the input like EOF, but is reported once
+	if len(b) == 0 && err != nil && err != io.EOF && l.readErr == nil {
+		l.readErr = err
+		l.error(l.currentPosition(), "read error: %s", err)
+	}
+	return b
 }
 
-func (l *Lexer) peekChar() byte {
-	if l.readPosition >= len(l.input) {
+func (l *Lexer) peekChar() rune {
+	b := l.peekBytes()
+	if len(b) == 0 {
 		return 0
-	} else {
-		return l.input[l.readPosition]
 	}
+	ch, _ := utf8.DecodeRune(b)
+	return ch
+}
+
+// startLexeme starts collecting the raw text of a token at l.ch, the
+// text up to but not including l.ch is returned by text.
+func (l *Lexer) startLexeme() {
+	l.lexeme = l.lexeme[:0]
+}
+
+func (l *Lexer) text() string {
+	return string(l.lexeme)
 }
 
 func (l *Lexer) readIdentifier() string {
-	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || unicode.IsDigit(l.ch) {
 		l.readChar()
 	}
 
-	return l.input[position:l.position]
+	return l.text()
+}
+
+var baseNames = map[int]string{
+	2:  "binary",
+	8:  "octal",
+	10: "decimal",
+	16: "hexadecimal",
 }
 
-func (l *Lexer) readNumber() string {
-	position := l.position
-	for isDigit(l.ch) {
+// readNumber reads an integer in decimal, hexadecimal (0x), octal (0o) or
+// binary (0b) notation, or a decimal float like 1.5e-3. Digits may be
+// separated by underscores. Malformed numbers are reported and come back
+// as ILLEGAL.
+func (l *Lexer) readNumber() (token.TokenType, string) {
+	pos := l.currentPosition()
+	tokType := token.TokenType(token.INT)
+
+	base := 10
+	if l.ch == '0' {
+		switch unicode.ToLower(l.peekChar()) {
+		case 'x':
+			base = 16
+		case 'o':
+			base = 8
+		case 'b':
+			base = 2
+		}
+		if base != 10 {
+			l.readChar()
+			l.readChar()
+		}
+	}
+
+	digits, invalid := l.readDigits(base)
+	if base == 10 {
+		if l.ch == '.' && isDigit(l.peekChar()) {
+			tokType = token.FLOAT
+			l.readChar()
+			l.readDigits(10)
+		}
+
+		if l.ch == 'e' || l.ch == 'E' {
+			tokType = token.FLOAT
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			if exponent, _ := l.readDigits(10); exponent == 0 {
+				l.error(pos, "exponent has no digits")
+				return token.ILLEGAL, l.text()
+			}
+		}
+	}
+
+	literal := l.text()
+	switch {
+	case digits == 0:
+		l.error(pos, "%s literal has no digits", baseNames[base])
+		tokType = token.ILLEGAL
+	case invalid != 0:
+		l.error(pos, "invalid digit %q in %s literal", invalid, baseNames[base])
+		tokType = token.ILLEGAL
+	case !validSeparators(literal, base):
+		l.error(pos, "'_' must separate successive digits")
+		tokType = token.ILLEGAL
+	}
+
+	return tokType, literal
+}
+
+// readDigits reads digits of the given base and underscores. It returns the
+// number of digits read and the first decimal digit that is too large for
+// the base, so that 0b102 is reported instead of lexed as 0b10 and 2.
+func (l *Lexer) readDigits(base int) (digits int, invalid rune) {
+	for {
+		switch {
+		case l.ch == '_':
+		case digitValue(l.ch) < base:
+			digits += 1
+		case isDigit(l.ch):
+			if invalid == 0 {
+				invalid = l.ch
+			}
+			digits += 1
+		default:
+			return digits, invalid
+		}
 		l.readChar()
 	}
+}
+
+// validSeparators reports whether every underscore in the number literal
+// sits between two digits, or right after the base prefix.
+func validSeparators(literal string, base int) bool {
+	isBaseDigit := func(ch byte) bool { return digitValue(rune(ch)) < base }
+	if base == 10 {
+		isBaseDigit = func(ch byte) bool { return isDigit(rune(ch)) }
+	}
+
+	for i := 0; i < len(literal); i++ {
+		if literal[i] != '_' {
+			continue
+		}
+
+		afterPrefix := base != 10 && i == 2
+		if i == 0 || !(afterPrefix || isBaseDigit(literal[i-1])) {
+			return false
+		}
+		if i+1 == len(literal) || !isBaseDigit(literal[i+1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func digitValue(ch rune) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'f':
+		return int(ch - 'a' + 10)
+	case 'A' <= ch && ch <= 'F':
+		return int(ch - 'A' + 10)
+	default:
+		return 16
+	}
+}
+
+// readString reads a double quoted string starting at the opening quote
+// and returns its value with the escape sequences resolved. ok is false
+// when the string is not terminated.
+func (l *Lexer) readString() (value string, ok bool) {
+	var out strings.Builder
+	start := l.currentPosition()
 
-	return l.input[position:l.position]
+	for {
+		l.readChar()
+		switch {
+		case l.ch == '"':
+			return out.String(), true
+		case l.atEOF():
+			l.error(start, "unterminated string literal")
+			return out.String(), false
+		case l.ch == '\\':
+			l.readEscape(&out)
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && 'z' >= ch || 'A' <= ch && 'Z' >= ch || ch == '_'
+// readEscape is called with l.ch on the backslash and leaves l.ch on the
+// last character of the escape sequence.
+func (l *Lexer) readEscape(out *strings.Builder) {
+	pos := l.currentPosition()
+
+	if len(l.peekBytes()) == 0 {
+		// let readString report the unterminated string
+		return
+	}
+
+	switch l.peekChar() {
+	case 'n':
+		out.WriteRune('\n')
+	case 't':
+		out.WriteRune('\t')
+	case 'r':
+		out.WriteRune('\r')
+	case '"':
+		out.WriteRune('"')
+	case '\\':
+		out.WriteRune('\\')
+	case 'u':
+		l.readChar()
+		l.readUnicodeEscape(pos, out)
+		return
+	default:
+		l.error(pos, "invalid escape sequence \\%c", l.peekChar())
+		out.WriteRune('\\')
+		return
+	}
+	l.readChar()
 }
 
-func isDigit(ch byte) bool {
+// readUnicodeEscape reads the {...} part of a \u{...} escape, l.ch is on
+// the 'u'.
+func (l *Lexer) readUnicodeEscape(pos token.Position, out *strings.Builder) {
+	if l.peekChar() != '{' {
+		l.error(pos, "invalid unicode escape, expected \\u{...}")
+		return
+	}
+	l.readChar()
+
+	var digits strings.Builder
+	for isHexDigit(l.peekChar()) {
+		l.readChar()
+		digits.WriteRune(l.ch)
+	}
+
+	if l.peekChar() != '}' || digits.Len() == 0 || digits.Len() > 6 {
+		l.error(pos, "invalid unicode escape, expected 1 to 6 hex digits in \\u{...}")
+		return
+	}
+	l.readChar()
+
+	code, _ := strconv.ParseUint(digits.String(), 16, 32)
+	r := rune(code)
+	if !utf8.ValidRune(r) {
+		l.error(pos, "invalid unicode escape, U+%X is not a valid code point", code)
+		return
+	}
+	out.WriteRune(r)
+}
+
+func isHexDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+// number literals only use ascii digits, other unicode digits may only
+// appear inside identifiers
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func newToken(tokType token.TokenType, ch byte) token.Token {
+func newToken(tokType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokType, Literal: string(ch)}
 }
 
+// readTwoCharToken is called on the first character of an operator like
+// == and leaves l.ch on the second one
+func (l *Lexer) readTwoCharToken(tokType token.TokenType) token.Token {
+	ch := l.ch
+	l.readChar()
+	literal := string(ch) + string(l.ch)
+	return token.Token{Type: tokType, Literal: literal}
+}
+
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
 func (l *Lexer) skipWhiteSpace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
 		l.readChar()
 	}
 }
 
+func (l *Lexer) atComment() bool {
+	return l.ch == '/' && (l.peekChar() == '/' || l.peekChar() == '*')
+}
+
+// readComment reads a // line comment up to the end of the line or a
+// /* block comment */, which may be nested. The returned text includes the
+// comment markers.
+func (l *Lexer) readComment() string {
+	start := l.currentPosition()
+	l.startLexeme()
+
+	if l.peekChar() == '/' {
+		for l.ch != '\n' && !l.atEOF() {
+			l.readChar()
+		}
+		return strings.TrimSuffix(l.text(), "\r")
+	}
+
+	l.readChar()
+	l.readChar()
+	depth := 1
+	for depth > 0 {
+		switch {
+		case l.atEOF():
+			l.error(start, "unterminated block comment")
+			return l.text()
+		case l.ch == '/' && l.peekChar() == '*':
+			depth += 1
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth -= 1
+			l.readChar()
+		}
+		l.readChar()
+	}
+
+	return l.text()
+}
+
+func (l *Lexer) atEOF() bool {
+	return l.eof
+}
+
 func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 	l.skipWhiteSpace()
+	for l.atComment() {
+		pos := l.currentPosition()
+		comment := l.readComment()
+		if l.mode&ScanComments != 0 {
+			return token.Token{Type: token.COMMENT, Literal: comment, Pos: pos, End: l.currentPosition()}
+		}
+		l.skipWhiteSpace()
+	}
+
+	pos := l.currentPosition()
+	l.startLexeme()
+
+	// a NUL byte in the input is an illegal character, only the end of
+	// the input is EOF
+	if l.atEOF() {
+		return token.Token{Type: token.EOF, Pos: pos, End: pos}
+	}
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.EQ, Literal: literal}
+			tok = l.readTwoCharToken(token.EQ)
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.PLUS_ASSIGN)
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.MINUS_ASSIGN)
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			literal := string(ch) + string(l.ch)
-			tok = token.Token{Type: token.NOT_EQ, Literal: literal}
+			tok = l.readTwoCharToken(token.NOT_EQ)
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.SLASH_ASSIGN)
+		} else {
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.ASTERISK_ASSIGN)
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '%':
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.PERCENT_ASSIGN)
+		} else {
+			tok = newToken(token.PERCENT, l.ch)
+		}
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.LT_EQ)
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.readTwoCharToken(token.GT_EQ)
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.readTwoCharToken(token.AND)
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+			l.error(pos, "illegal character %q, did you mean &&", tok.Literal)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.readTwoCharToken(token.OR)
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+			l.error(pos, "illegal character %q, did you mean ||", tok.Literal)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case '{':
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
@@ -115,23 +566,44 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
 		tok = newToken(token.RPAREN, l.ch)
-	case 0:
-		tok.Literal = ""
-		tok.Type = token.EOF
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
+	case '"':
+		value, ok := l.readString()
+		if ok {
+			tok.Type = token.STRING
+			tok.Literal = value
+		} else {
+			tok.Type = token.ILLEGAL
+			tok.Literal = l.text()
+			tok.Pos = pos
+			tok.End = l.currentPosition()
+			return tok
+		}
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
+			tok.End = l.currentPosition()
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Type, tok.Literal = l.readNumber()
+			tok.Pos = pos
+			tok.End = l.currentPosition()
 			return tok
 		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+			// keep the raw bytes so invalid utf-8 is not replaced by U+FFFD
+			tok.Type = token.ILLEGAL
+			tok.Literal = string(l.chBytes[:l.chSize])
+			l.error(pos, "illegal character %q", tok.Literal)
 		}
 	}
 
 	l.readChar()
+	tok.Pos = pos
+	tok.End = l.currentPosition()
 	return tok
 }
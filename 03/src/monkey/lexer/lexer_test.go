@@ -1,8 +1,13 @@
 package lexer
 
 import (
-	"testing"
+	"errors"
+	"io"
 	"monkey/token"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
 )
 
 func TestNextToken(t *testing.T) {
@@ -14,7 +19,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -25,6 +30,9 @@ if (5 < 10) {
 
 10 == 10;
 10 != 9;
+[1, 2];
+{"foo": "bar"}
+while for in break continue
 `
 
 	tests := []struct {
@@ -104,6 +112,429 @@ if (5 < 10) {
 		{token.NOT_EQ, "!="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACE, "{"},
+		{token.STRING, "foo"},
+		{token.COLON, ":"},
+		{token.STRING, "bar"},
+		{token.RBRACE, "}"},
+		{token.WHILE, "while"},
+		{token.FOR, "for"},
+		{token.IN, "in"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  if (x) {\n\treturn 10;\n}"
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedOffset int
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 0, 1, 1},
+		{token.IDENT, 4, 1, 5},
+		{token.ASSIGN, 6, 1, 7},
+		{token.INT, 8, 1, 9},
+		{token.SEMICOLON, 9, 1, 10},
+		{token.IF, 13, 2, 3},
+		{token.LPAREN, 16, 2, 6},
+		{token.IDENT, 17, 2, 7},
+		{token.RPAREN, 18, 2, 8},
+		{token.LBRACE, 20, 2, 10},
+		{token.RETURN, 23, 3, 2},
+		{token.INT, 30, 3, 9},
+		{token.SEMICOLON, 32, 3, 11},
+		{token.RBRACE, 34, 4, 1},
+		{token.EOF, 35, 4, 2},
+	}
+
+	l := NewFile("test.mk", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos.Filename != "test.mk" {
+			t.Errorf("tests[%d] - filename wrong. expected=%q, got=%q",
+				i, "test.mk", tok.Pos.Filename)
+		}
+
+		if tok.Pos.Offset != tt.expectedOffset || tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position wrong. expected=%d:%d (offset %d), got=%d:%d (offset %d)",
+				i, tt.expectedLine, tt.expectedColumn, tt.expectedOffset,
+				tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	input := "let größe = 5;\nlet 名前 = x1 + π;\n€"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedOffset  int
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{token.LET, "let", 0, 1, 1},
+		{token.IDENT, "größe", 4, 1, 5},
+		{token.ASSIGN, "=", 12, 1, 11},
+		{token.INT, "5", 14, 1, 13},
+		{token.SEMICOLON, ";", 15, 1, 14},
+		{token.LET, "let", 17, 2, 1},
+		{token.IDENT, "名前", 21, 2, 5},
+		{token.ASSIGN, "=", 28, 2, 8},
+		{token.IDENT, "x1", 30, 2, 10},
+		{token.PLUS, "+", 33, 2, 13},
+		{token.IDENT, "π", 35, 2, 15},
+		{token.SEMICOLON, ";", 37, 2, 16},
+		{token.ILLEGAL, "€", 39, 3, 1},
+		{token.EOF, "", 42, 3, 2},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Offset != tt.expectedOffset || tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position wrong. expected=%d:%d (offset %d), got=%d:%d (offset %d)",
+				i, tt.expectedLine, tt.expectedColumn, tt.expectedOffset,
+				tok.Pos.Line, tok.Pos.Column, tok.Pos.Offset)
+		}
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	l := New("a \xff b")
+
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.ILLEGAL, "\xff"},
+		{token.IDENT, "b"},
+		{token.EOF, ""},
+	}
+
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected %q %q, got %q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedEnd     int
+	}{
+		{`"foobar"`, token.STRING, "foobar", 8},
+		{`"foo bar"`, token.STRING, "foo bar", 9},
+		{`""`, token.STRING, "", 2},
+		{`"a\nb\tc"`, token.STRING, "a\nb\tc", 9},
+		{`"say \"hi\" \\o/"`, token.STRING, `say "hi" \o/`, 17},
+		{`"\u{41}\u{e9}\u{1F600}"`, token.STRING, "Aé\U0001F600", 23},
+		{"\"größe\"", token.STRING, "größe", 9},
+		{"\"two\nlines\"", token.STRING, "two\nlines", 11},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		l.SetErrorHandler(func(pos token.Position, msg string) {
+			t.Errorf("tests[%d] - unexpected error %s: %s", i, pos, msg)
+		})
+
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.End.Offset != tt.expectedEnd {
+			t.Errorf("tests[%d] - end offset wrong. expected=%d, got=%d", i, tt.expectedEnd, tok.End.Offset)
+		}
+
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("tests[%d] - expected EOF after the string, got %q", i, tok.Type)
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedType   token.TokenType
+		expectedErrors []string
+	}{
+		{`"unterminated`, token.ILLEGAL, []string{"1:1: unterminated string literal"}},
+		{"let s = \"abc\\", token.ILLEGAL, []string{"1:9: unterminated string literal"}},
+		{`"bad \q escape"`, token.STRING, []string{`1:6: invalid escape sequence \q`}},
+		{`"\u0041"`, token.STRING, []string{`1:2: invalid unicode escape, expected \u{...}`}},
+		{`"\u{}"`, token.STRING, []string{`1:2: invalid unicode escape, expected 1 to 6 hex digits in \u{...}`}},
+		{`"\u{1234567}"`, token.STRING, []string{`1:2: invalid unicode escape, expected 1 to 6 hex digits in \u{...}`}},
+		{`"\u{D800}"`, token.STRING, []string{`1:2: invalid unicode escape, U+D800 is not a valid code point`}},
+		{"\"ok\" @", token.ILLEGAL, []string{`1:6: illegal character "@"`}},
+		{"a\x00b", token.IDENT, []string{`1:2: illegal character "\x00"`}},
+		{"\"a\\\x00\"", token.STRING, []string{"1:3: invalid escape sequence \\\x00"}},
+	}
+
+	for i, tt := range tests {
+		errors := []string{}
+		l := New(tt.input)
+		l.SetErrorHandler(func(pos token.Position, msg string) {
+			errors = append(errors, pos.String()+": "+msg)
+		})
+
+		var last token.Token
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			last = tok
+		}
+
+		if last.Type != tt.expectedType {
+			t.Errorf("tests[%d] - last tokentype wrong. expected=%q, got=%q", i, tt.expectedType, last.Type)
+		}
+
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("tests[%d] - expected errors %q, got %q", i, tt.expectedErrors, errors)
+			continue
+		}
+
+		for j, msg := range tt.expectedErrors {
+			if errors[j] != msg {
+				t.Errorf("tests[%d] - error wrong. expected=%q, got=%q", i, msg, errors[j])
+			}
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let x = 5; // trailing comment
+/* block */ let /* inline */ y = x /
+  2;
+/* outer /* nested */ still a comment */
+/**/ x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "// leading comment"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "// trailing comment"},
+		{token.COMMENT, "/* block */"},
+		{token.LET, "let"},
+		{token.COMMENT, "/* inline */"},
+		{token.IDENT, "y"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "/* outer /* nested */ still a comment */"},
+		{token.COMMENT, "/**/"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	// without ScanComments the same input lexes as if the comments were
+	// not there
+	l := New(input)
+	for _, tt := range tests {
+		if tt.expectedType == token.COMMENT {
+			continue
+		}
+
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("skipping comments - expected %q %q, got %q %q",
+				tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+
+	l = New(input)
+	l.SetMode(ScanComments)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - expected %q %q, got %q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+
+		if tok.Type == token.COMMENT && input[tok.Pos.Offset:tok.End.Offset] != tok.Literal {
+			t.Errorf("tests[%d] - comment span %q does not match literal %q",
+				i, input[tok.Pos.Offset:tok.End.Offset], tok.Literal)
+		}
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	input := "let x = 1;\n/* outer /* inner */ never closed"
+
+	errors := []string{}
+	l := New(input)
+	l.SetErrorHandler(func(pos token.Position, msg string) {
+		errors = append(errors, pos.String()+": "+msg)
+	})
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+
+	if len(errors) != 1 || errors[0] != "2:1: unterminated block comment" {
+		t.Errorf(" expected one unterminated block comment error, got %q ", errors)
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedError   string
+	}{
+		{"12345", token.INT, "12345", ""},
+		{"1_000_000", token.INT, "1_000_000", ""},
+		{"0x1F_ff", token.INT, "0x1F_ff", ""},
+		{"0X_ab", token.INT, "0X_ab", ""},
+		{"0o755", token.INT, "0o755", ""},
+		{"0b1010_0101", token.INT, "0b1010_0101", ""},
+		{"3.14159", token.FLOAT, "3.14159", ""},
+		{"1e10", token.FLOAT, "1e10", ""},
+		{"1.5e-3", token.FLOAT, "1.5e-3", ""},
+		{"6.02E+23", token.FLOAT, "6.02E+23", ""},
+		{"0x", token.ILLEGAL, "0x", "1:1: hexadecimal literal has no digits"},
+		{"0b102", token.ILLEGAL, "0b102", "1:1: invalid digit '2' in binary literal"},
+		{"0o8", token.ILLEGAL, "0o8", "1:1: invalid digit '8' in octal literal"},
+		{"1e", token.ILLEGAL, "1e", "1:1: exponent has no digits"},
+		{"1.5e+", token.ILLEGAL, "1.5e+", "1:1: exponent has no digits"},
+		{"1__000", token.ILLEGAL, "1__000", "1:1: '_' must separate successive digits"},
+		{"1000_", token.ILLEGAL, "1000_", "1:1: '_' must separate successive digits"},
+		{"1_e5", token.ILLEGAL, "1_e5", "1:1: '_' must separate successive digits"},
+	}
+
+	for i, tt := range tests {
+		errors := []string{}
+		l := New(tt.input)
+		l.SetErrorHandler(func(pos token.Position, msg string) {
+			errors = append(errors, pos.String()+": "+msg)
+		})
+
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Errorf("tests[%d] - expected %q %q, got %q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+
+		if tt.expectedError == "" && len(errors) != 0 {
+			t.Errorf("tests[%d] - unexpected errors %q", i, errors)
+		}
+		if tt.expectedError != "" && (len(errors) != 1 || errors[0] != tt.expectedError) {
+			t.Errorf("tests[%d] - expected error %q, got %q", i, tt.expectedError, errors)
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	input := `a <= b >= c < d > e; x && y || !z; 10 % 3; a & b | c; a = b += c -= d *= e /= f %= g`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.LT, "<"},
+		{token.IDENT, "d"},
+		{token.GT, ">"},
+		{token.IDENT, "e"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.AND, "&&"},
+		{token.IDENT, "y"},
+		{token.OR, "||"},
+		{token.BANG, "!"},
+		{token.IDENT, "z"},
+		{token.SEMICOLON, ";"},
+		{token.INT, "10"},
+		{token.PERCENT, "%"},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.ILLEGAL, "&"},
+		{token.IDENT, "b"},
+		{token.ILLEGAL, "|"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "b"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.IDENT, "c"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.IDENT, "d"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.IDENT, "e"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.IDENT, "f"},
+		{token.PERCENT_ASSIGN, "%="},
+		{token.IDENT, "g"},
 		{token.EOF, ""},
 	}
 
@@ -121,5 +552,100 @@ if (5 < 10) {
 			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
 				i, tt.expectedLiteral, tok.Literal)
 		}
+
+		if tok.End.Offset-tok.Pos.Offset != len(tt.expectedLiteral) {
+			t.Errorf("tests[%d] - span of %q is %d bytes", i, tok.Literal, tok.End.Offset-tok.Pos.Offset)
+		}
+	}
+}
+
+type lexedToken struct {
+	tok token.Token
+	err string
+}
+
+func lexAll(l *Lexer) []lexedToken {
+	result := []lexedToken{}
+	l.SetErrorHandler(func(pos token.Position, msg string) {
+		result = append(result, lexedToken{err: pos.String() + ": " + msg})
+	})
+
+	for {
+		tok := l.NextToken()
+		result = append(result, lexedToken{tok: tok})
+		if tok.Type == token.EOF {
+			return result
+		}
+	}
+}
+
+func TestReaderMatchesString(t *testing.T) {
+	inputs := []string{
+		"",
+		"let five = 5;\nlet add = fn(x, y) {\n  x + y;\n};\nadd(five, 10);",
+		"if (5 <= 10) { return true && !false; } else { return 10 % 3 >= 1 || x; }",
+		"let größe = 5;\nlet 名前 = x1 + π;\n€",
+		"a \xff b \xe2\x82",
+		`"a\nb\tc" "say \"hi\"" "\u{1F600}" "bad \q" "\u{D800}" "unterminated`,
+		"0x1F_ff 0o755 0b101 1_000 3.14 6.02E+23 0b102 1e 1__0",
+		"// line\r\nx /* block /* nested */ */ y // end",
+		"/* never closed",
+		"a & b | c @",
+	}
+
+	for i, input := range inputs {
+		expected := lexAll(New(input))
+
+		readers := map[string]io.Reader{
+			"whole":    strings.NewReader(input),
+			"one byte": iotest.OneByteReader(strings.NewReader(input)),
+			"half":     iotest.HalfReader(strings.NewReader(input)),
+			"data err": iotest.DataErrReader(strings.NewReader(input)),
+		}
+
+		for name, r := range readers {
+			actual := lexAll(NewReader(r))
+
+			if !reflect.DeepEqual(expected, actual) {
+				t.Errorf("inputs[%d] - %s reader differs from string lexer\nexpected=%+v\ngot=     %+v",
+					i, name, expected, actual)
+			}
+		}
+	}
+}
+
+func TestReaderComments(t *testing.T) {
+	input := "x // one\n/* two */ y"
+
+	stringLexer := New(input)
+	stringLexer.SetMode(ScanComments)
+	readerLexer := NewReader(iotest.OneByteReader(strings.NewReader(input)))
+	readerLexer.SetMode(ScanComments)
+
+	expected := lexAll(stringLexer)
+	actual := lexAll(readerLexer)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("reader differs from string lexer\nexpected=%+v\ngot=     %+v", expected, actual)
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	r := io.MultiReader(strings.NewReader("let x = 1"), iotest.ErrReader(errors.New("connection reset")))
+
+	tokens := lexAll(NewFileReader("net.mk", r))
+	var messages []string
+	for _, lt := range tokens {
+		if lt.err != "" {
+			messages = append(messages, lt.err)
+		}
+	}
+
+	if len(messages) != 1 || messages[0] != "net.mk:1:10: read error: connection reset" {
+		t.Errorf(" expected a single read error, got %q ", messages)
+	}
+
+	last := tokens[len(tokens)-1].tok
+	if last.Type != token.EOF {
+		t.Errorf(" expected the stream to end with EOF, got %q ", last.Type)
 	}
 }
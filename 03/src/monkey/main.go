@@ -5,15 +5,51 @@ import (
 	"monkey/repl"
 	"os"
 	"os/user"
+	"sort"
 )
 
+// subcommands of the monkey binary, without one the REPL is started
+var commands = map[string]func(args []string) int{
+	"fmt":    fmtCommand,
+	"parse":  parseCommand,
+	"tokens": tokensCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 
 	fmt.Printf("Howdy %s, Welcome to Monkey programming language \n", user.Username)
-	fmt.Printf("Feel free to type coments for Lexing! ")
+	fmt.Printf("Feel free to type in commands \n")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+func runCommand(name string, args []string) int {
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "monkey: unknown command %q\n", name)
+		usage()
+		return 2
+	}
+	return command(args)
+}
+
+func usage() {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "usage: monkey [command] [arguments]\n\n")
+	fmt.Fprintf(os.Stderr, "without a command the interactive REPL is started, commands are:\n")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "\t%s\n", name)
+	}
+}
@@ -1,36 +1,244 @@
 package object
 
 import (
+	"bytes"
 	"fmt"
+	"hash/fnv"
+	"monkey/ast"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 type ObjectType string
+
 const (
-	INTEGER_OBJ = "INTEGER"
-	BOOLEAN_OBJ = "BOOLEAN"
-	NULL_OBJ = "NULL"
+	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
+	BOOLEAN_OBJ      = "BOOLEAN"
+	NULL_OBJ         = "NULL"
+	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	ERROR_OBJ        = "ERROR"
+	FUNCTION_OBJ     = "FUNCTION"
+	BUILTIN_OBJ      = "BUILTIN"
+	STRING_OBJ       = "STRING"
+	ARRAY_OBJ        = "ARRAY"
+	HASH_OBJ         = "HASH"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 )
 
-type Object interface{
+type Object interface {
 	Type() ObjectType
 	Inspect() string
 }
 
 type Integer struct {
 	Value int64
-}	
+}
+
+func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
+func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	// keep whole numbers recognisable as floats
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
 
-func (i *Integer) Inspect() string {return fmt.Sprintf("%d",i.Value)}
-func (i *Integer) Type() string { return INTEGER_OBJ }
- 
 type Boolean struct {
 	Value bool
 }
 
-func (b *Boolean) Inspect() string {return fmt.Sprintf("%t",b.Value)}
-func (b *Boolean) Type() string {return BOOLEAN_OBJ}
+func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
+func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+type Null struct{}
+
+func (n *Null) Inspect() string  { return "null" }
+func (n *Null) Type() ObjectType { return NULL_OBJ }
+
+// break and continue travel up through the block statements of a loop
+// body the same way a return value does, the loop consumes them
+type Break struct{}
+
+func (b *Break) Inspect() string  { return "break" }
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+
+type Continue struct{}
+
+func (c *Continue) Inspect() string  { return "continue" }
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+
+// return value wraps the value of a return statement so that it can
+// bubble up through nested block statements
+type ReturnValue struct {
+	Value Object
+}
+
+func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
+func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
+
+// error
+type Error struct {
+	Message string
+}
+
+func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Type() ObjectType { return ERROR_OBJ }
+
+// function closes over the environment it was defined in
+type Function struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+func (f *Function) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("fn(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(f.Body.String())
+
+	return out.String()
+}
+
+// builtin functions are implemented in go and called like any other
+// function value
+type BuiltinFunction func(args ...Object) Object
 
-type Null struct {}
+type Builtin struct {
+	Fn BuiltinFunction
+}
+
+func (b *Builtin) Inspect() string  { return "builtin function" }
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+
+// string
+type String struct {
+	Value string
+}
+
+func (s *String) Inspect() string  { return s.Value }
+func (s *String) Type() ObjectType { return STRING_OBJ }
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+// array
+type Array struct {
+	Elements []Object
+}
 
-func (n *Null) Inspect() string { return "null"}
-func (n *Null) Type() string {return NULL_OBJ}
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string  { return inspect(a, map[Object]bool{}) }
+
+func (a *Array) inspect(printing map[Object]bool) string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, inspect(e, printing))
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// inspect prints obj, arrays and hashes can contain themselves after an
+// index assignment so one that is already being printed shows up as [...]
+// or {...} instead of recursing forever
+func inspect(obj Object, printing map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		if printing[obj] {
+			return "[...]"
+		}
+		printing[obj] = true
+		defer delete(printing, obj)
+		return obj.inspect(printing)
+	case *Hash:
+		if printing[obj] {
+			return "{...}"
+		}
+		printing[obj] = true
+		defer delete(printing, obj)
+		return obj.inspect(printing)
+	default:
+		return obj.Inspect()
+	}
+}
+
+// hash key identifies a hashable object by type and value, so that two
+// different *String with the same contents end up in the same slot
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// only objects implementing Hashable can be used as keys of a Hash
+type Hashable interface {
+	HashKey() HashKey
+}
+
+// the original key is kept next to the value so that Inspect and
+// iteration can show it
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs map[HashKey]HashPair
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string  { return inspect(h, map[Object]bool{}) }
+
+func (h *Hash) inspect(printing map[Object]bool) string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, inspect(pair.Key, printing)+": "+inspect(pair.Value, printing))
+	}
+	// map iteration order is random, sort to keep the output stable
+	sort.Strings(pairs)
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -0,0 +1,172 @@
+package object
+
+import (
+	"monkey/ast"
+	"monkey/token"
+	"testing"
+)
+
+func TestObjectTypeAndInspect(t *testing.T) {
+	fnBody := &ast.BlockStatement{
+		Token: token.Token{Type: token.LBRACE, Literal: "{"},
+		Statements: []ast.Statement{
+			&ast.ExpressionStatement{
+				Token: token.Token{Type: token.IDENT, Literal: "x"},
+				Expression: &ast.Identifier{
+					Token: token.Token{Type: token.IDENT, Literal: "x"},
+					Value: "x",
+				},
+			},
+		},
+	}
+	fnParams := []*ast.Identifier{
+		{Token: token.Token{Type: token.IDENT, Literal: "x"}, Value: "x"},
+		{Token: token.Token{Type: token.IDENT, Literal: "y"}, Value: "y"},
+	}
+
+	tests := []struct {
+		obj             Object
+		expectedType    ObjectType
+		expectedInspect string
+	}{
+		{&Integer{Value: 42}, INTEGER_OBJ, "42"},
+		{&Integer{Value: -7}, INTEGER_OBJ, "-7"},
+		{&Float{Value: 1.5}, FLOAT_OBJ, "1.5"},
+		{&Float{Value: 2}, FLOAT_OBJ, "2.0"},
+		{&Float{Value: 1e21}, FLOAT_OBJ, "1e+21"},
+		{&Float{Value: -0.001}, FLOAT_OBJ, "-0.001"},
+		{&Boolean{Value: true}, BOOLEAN_OBJ, "true"},
+		{&Boolean{Value: false}, BOOLEAN_OBJ, "false"},
+		{&Null{}, NULL_OBJ, "null"},
+		{&Break{}, BREAK_OBJ, "break"},
+		{&Continue{}, CONTINUE_OBJ, "continue"},
+		{&ReturnValue{Value: &Integer{Value: 5}}, RETURN_VALUE_OBJ, "5"},
+		{&Error{Message: "type mismatch: INTEGER + BOOLEAN"}, ERROR_OBJ, "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{&Function{Parameters: fnParams, Body: fnBody, Env: NewEnvironment()}, FUNCTION_OBJ, "fn(x, y) { x }"},
+		{&Builtin{Fn: func(args ...Object) Object { return nil }}, BUILTIN_OBJ, "builtin function"},
+		{&String{Value: "hello world"}, STRING_OBJ, "hello world"},
+		{&Array{Elements: []Object{}}, ARRAY_OBJ, "[]"},
+		{&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "two"}, &Boolean{Value: true}}}, ARRAY_OBJ, "[1, two, true]"},
+		{&Hash{Pairs: map[HashKey]HashPair{}}, HASH_OBJ, "{}"},
+		{
+			&Hash{Pairs: map[HashKey]HashPair{
+				(&String{Value: "b"}).HashKey(): {Key: &String{Value: "b"}, Value: &Integer{Value: 2}},
+				(&String{Value: "a"}).HashKey(): {Key: &String{Value: "a"}, Value: &Integer{Value: 1}},
+			}},
+			HASH_OBJ,
+			"{a: 1, b: 2}",
+		},
+	}
+
+	for i, tt := range tests {
+		if tt.obj.Type() != tt.expectedType {
+			t.Errorf("tests[%d] - type wrong. expected=%q, got=%q", i, tt.expectedType, tt.obj.Type())
+		}
+
+		if tt.obj.Inspect() != tt.expectedInspect {
+			t.Errorf("tests[%d] - inspect wrong. expected=%q, got=%q", i, tt.expectedInspect, tt.obj.Inspect())
+		}
+	}
+}
+
+func TestInspectSelfReference(t *testing.T) {
+	array := &Array{Elements: []Object{&Integer{Value: 1}}}
+	array.Elements = append(array.Elements, array)
+
+	key := &String{Value: "s"}
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	hash.Pairs[key.HashKey()] = HashPair{Key: key, Value: hash}
+
+	// shared but not cyclic values are printed in full every time
+	shared := &Array{Elements: []Object{&Integer{Value: 2}}}
+	twice := &Array{Elements: []Object{shared, shared}}
+
+	nested := &Array{Elements: []Object{hash, array}}
+
+	tests := []struct {
+		obj             Object
+		expectedInspect string
+	}{
+		{array, "[1, [...]]"},
+		{hash, "{s: {...}}"},
+		{twice, "[[2], [2]]"},
+		{nested, "[{s: {...}}, [1, [...]]]"},
+	}
+
+	for i, tt := range tests {
+		if tt.obj.Inspect() != tt.expectedInspect {
+			t.Errorf("tests[%d] - inspect wrong. expected=%q, got=%q", i, tt.expectedInspect, tt.obj.Inspect())
+		}
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	tests := []struct {
+		left  Hashable
+		right Hashable
+		equal bool
+	}{
+		{&String{Value: "Hello World"}, &String{Value: "Hello World"}, true},
+		{&String{Value: "Hello World"}, &String{Value: "My name is johnny"}, false},
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &Integer{Value: 2}, false},
+		{&Boolean{Value: true}, &Boolean{Value: true}, true},
+		{&Boolean{Value: true}, &Boolean{Value: false}, false},
+		{&Integer{Value: 1}, &Boolean{Value: true}, false},
+		{&Integer{Value: 0}, &Boolean{Value: false}, false},
+	}
+
+	for i, tt := range tests {
+		if (tt.left.HashKey() == tt.right.HashKey()) != tt.equal {
+			t.Errorf("tests[%d] - hash keys of %T and %T equal should be %t", i, tt.left, tt.right, tt.equal)
+		}
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Integer{Value: 1})
+
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("b", &Integer{Value: 2})
+
+	if _, ok := outer.Get("b"); ok {
+		t.Errorf(" outer environment sees inner binding b ")
+	}
+
+	val, ok := inner.Get("a")
+	if !ok || val.Inspect() != "1" {
+		t.Errorf(" inner environment cannot see outer binding a, got %v ", val)
+	}
+
+	inner.Set("a", &Integer{Value: 3})
+	if val, _ := outer.Get("a"); val.Inspect() != "1" {
+		t.Errorf(" shadowing in inner changed outer a to %s ", val.Inspect())
+	}
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+
+	if _, ok := inner.Assign("a", &Integer{Value: 2}); !ok {
+		t.Fatalf(" assigning to outer binding a failed ")
+	}
+
+	if val, _ := outer.Get("a"); val.Inspect() != "2" {
+		t.Errorf(" outer a was not updated, got %s ", val.Inspect())
+	}
+
+	if _, ok := inner.store["a"]; ok {
+		t.Errorf(" assign created a new binding in the inner environment ")
+	}
+
+	if _, ok := inner.Assign("b", &Integer{Value: 3}); ok {
+		t.Errorf(" assigning to undeclared b succeeded ")
+	}
+
+	if _, ok := outer.Get("b"); ok {
+		t.Errorf(" assigning to undeclared b created a binding ")
+	}
+}
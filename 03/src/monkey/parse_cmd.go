@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+)
+
+// parseCommand implements monkey parse [-json | -dot | -sexpr] [file]. It
+// prints the parsed program, as the String of the ast, as JSON for tools
+// written in other languages, or as a Graphviz graph or S-expression that
+// show how the expressions were grouped. Without a file it parses standard
+// input.
+func parseCommand(args []string) int {
+	flags := flag.NewFlagSet("parse", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the ast as JSON")
+	asDOT := flags.Bool("dot", false, "print the ast as a Graphviz digraph")
+	asSExpr := flags.Bool("sexpr", false, "print the ast as an S-expression")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: monkey parse [-json | -dot | -sexpr] [file]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	formats := 0
+	for _, set := range []bool{*asJSON, *asDOT, *asSExpr} {
+		if set {
+			formats++
+		}
+	}
+	if flags.NArg() > 1 || formats > 1 {
+		flags.Usage()
+		return 2
+	}
+
+	filename, src, err := readInput(flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey parse: %s\n", err)
+		return 1
+	}
+
+	p := parser.New(lexer.NewFile(filename, string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range parser.Messages(p.Errors()) {
+			fmt.Fprintln(os.Stderr, msg)
+		}
+		return 1
+	}
+
+	switch {
+	case *asDOT:
+		fmt.Print(ast.DOT(program))
+		return 0
+	case *asSExpr:
+		fmt.Print(ast.SExpr(program))
+		return 0
+	case !*asJSON:
+		fmt.Println(program.String())
+		return 0
+	}
+
+	out, err := json.MarshalIndent(program, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monkey parse: %s\n", err)
+		return 1
+	}
+	os.Stdout.Write(append(out, '\n'))
+	return 0
+}
+
+// readInput reads the single file in args, or standard input when there
+// is none
+func readInput(args []string) (string, []byte, error) {
+	if len(args) == 0 {
+		src, err := ioutil.ReadAll(os.Stdin)
+		return "<stdin>", src, err
+	}
+	src, err := ioutil.ReadFile(args[0])
+	return args[0], src, err
+}
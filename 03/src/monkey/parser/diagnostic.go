@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/token"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Code identifies the kind of a diagnostic. The values are stable so that
+// tools can match on them instead of on the message text.
+type Code string
+
+const (
+	// a specific token was required, Expected holds it
+	ErrUnexpectedToken Code = "E001"
+	// the token cannot start an expression
+	ErrNoPrefixParseFn Code = "E002"
+	// a number literal does not fit into int64 or float64
+	ErrNumberOutOfRange Code = "E003"
+	// a number literal could not be converted at all
+	ErrInvalidNumber Code = "E004"
+	// the lexer could not make sense of the input, like an unterminated
+	// string or an unknown character
+	ErrIllegalToken Code = "E005"
+	// break or continue used outside of a loop body
+	ErrOutsideLoop Code = "E006"
+	// the left side of an assignment is not a name or an index expression
+	ErrInvalidAssignment Code = "E007"
+)
+
+// Diagnostic describes a problem found while parsing. Pos and End span the
+// offending source, Actual is the token that was found there and Expected,
+// when known, lists the token types that would have been accepted.
+type Diagnostic struct {
+	Severity Severity
+	Code     Code
+	Pos      token.Position
+	End      token.Position
+	Message  string
+	Expected []token.TokenType
+	Actual   token.Token
+}
+
+// String renders the diagnostic as position: message.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Message)
+}
+
+func (d Diagnostic) Error() string { return d.String() }
+
+// Messages renders every diagnostic with String, for callers that only
+// want to print them.
+func Messages(diagnostics []Diagnostic) []string {
+	messages := make([]string, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		messages = append(messages, d.String())
+	}
+	return messages
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"testing"
+)
+
+// seeds are inputs from the parser tests, valid programs as well as the
+// broken ones used for the error messages and recovery
+var seeds = []string{
+	"return 5;\nreturn 10;\nreturn 993322;",
+	"let x = 5;\nlet y = 10;\nlet foobar = 838383;",
+	"-a * b",
+	"a + b * c + d / e - f",
+	"3 + 4; -5 * 5",
+	"!(true == true)",
+	"a * [1, 2, 3, 4][b * c] * d",
+	"add(a * b[2], b[1], 2 * [1, 2][1])",
+	"a || b && c == d < e <= f + g * -h % i",
+	"x = y += 1",
+	"if (x < y) { x } else if (x > y) { y } else if (z) { z } else { 0 }",
+	"fn(x, y) { x + y; }",
+	"fn() {}",
+	"add (1, 2 * 3, 3 - 4);",
+	`{"one": 0 + 1, true: 10 - 8, 3: 15 / 5}`,
+	`if (x) { {"a": 1} } else { {} }`,
+	"while (x < y) { x; break; continue; }",
+	"for x in [1, 2] { x; }",
+	"while (x) { fn() { break; } }",
+	"a[0] = 1; h[\"k\"] *= 2",
+	"0xff + 0o17 + 0b101 + 1_000 + 1.5e-3",
+	`"hello \"world\"\n";`,
+	"let = 5;",
+	"let x 5;",
+	"add(1,\n  2",
+	"[1, 2",
+	"a[1;",
+	`{"a": 1 "b": 2}`,
+	"for x of y {}",
+	"1 = 2",
+	"f() = 1",
+	"99999999999999999999",
+	"1e999",
+	"fn(x { x }\nlet y = 2;",
+	"if (x > ) { 1 } else { 2 }; 3",
+	"let a = (1 + 2;\nlet b = (3 * ) + 4;\nlet c = 5;",
+	"if (a) { let x = } b",
+	"x; @; y",
+	"let s = \"abc\\q\"; let t = @;\nreturn s;",
+	"let f = fn(x) {\n  x + 1;\n",
+	"// comment\nlet x = 1; /* block */",
+}
+
+func FuzzParseProgram(f *testing.F) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+
+		// every node of a partial tree must still answer for its span
+		ast.Inspect(program, func(node ast.Node) bool {
+			if node != nil {
+				node.Pos()
+				node.End()
+			}
+			return true
+		})
+	})
+}
+
+func FuzzProgramString(f *testing.F) {
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		p := New(lexer.New(input))
+		program := p.ParseProgram()
+		source := program.String()
+		if len(p.Errors()) != 0 {
+			return
+		}
+
+		again := New(lexer.New(source))
+		reparsed := again.ParseProgram()
+		if len(again.Errors()) != 0 {
+			t.Fatalf(" %q is written as %q which does not parse: %q ", input, source, Messages(again.Errors()))
+		}
+		if reparsed.String() != source {
+			t.Fatalf(" %q is written as %q which parses back as %q ", input, source, reparsed.String())
+		}
+	})
+}
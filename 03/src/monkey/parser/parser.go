@@ -1,34 +1,52 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
 	"monkey/ast"
 	"monkey/lexer"
 	"monkey/token"
 	"strconv"
+	"strings"
 )
 
 const (
 	_ int = iota
 	LOWEST
+	ASSIGN
+	LOGICAL_OR
+	LOGICAL_AND
 	EQUALS
 	LESSGRATER
 	SUM
 	PRODUCT
 	PREFIX
 	CALL
+	INDEX
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGRATER,
-	token.GT:       LESSGRATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
+	token.ASSIGN:          ASSIGN,
+	token.PLUS_ASSIGN:     ASSIGN,
+	token.MINUS_ASSIGN:    ASSIGN,
+	token.ASTERISK_ASSIGN: ASSIGN,
+	token.SLASH_ASSIGN:    ASSIGN,
+	token.PERCENT_ASSIGN:  ASSIGN,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.OR:              LOGICAL_OR,
+	token.AND:             LOGICAL_AND,
+	token.LT:              LESSGRATER,
+	token.GT:              LESSGRATER,
+	token.LT_EQ:           LESSGRATER,
+	token.GT_EQ:           LESSGRATER,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -47,21 +65,35 @@ func (p *Parser) curPrecedence() int {
 
 type Parser struct {
 	l      *lexer.Lexer
-	errors []string
+	errors []Diagnostic
 
 	curToken  token.Token
 	peekToken token.Token
 
+	// panicking is set by the first error in a statement, further errors
+	// are dropped until the parser has skipped to the next statement
+	panicking bool
+	// blockDepth counts the block statements being parsed, so that error
+	// recovery knows whether a } closes a block it is in
+	blockDepth int
+	// loopDepth counts the loop bodies being parsed, function literals
+	// start over at 0 since break cannot leave a function
+	loopDepth int
+
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 }
 
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, errors: []Diagnostic{}}
+	l.SetErrorHandler(p.lexerError)
 	// parse Prefix Expression
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifer)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	// parse Infix Expression
@@ -74,8 +106,20 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PERCENT_ASSIGN, p.parseAssignExpression)
 
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
@@ -86,6 +130,9 @@ func New(l *lexer.Lexer) *Parser {
 
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+
 	p.nextToken()
 	p.nextToken()
 	return p
@@ -113,6 +160,10 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 	for p.curToken.Type != token.EOF {
 		stmt := p.parseStatement()
+		if p.panicking {
+			p.synchronize()
+			continue
+		}
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
@@ -122,12 +173,64 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// synchronize skips the rest of a statement that had an error. It stops on
+// the first token of the next statement, that is after a ; or on a let or
+// return keyword or the start of a loop, or on the } of the block being
+// parsed. Braces in between are skipped as a whole so that a broken
+// function body is dropped with the statement it belongs to.
+func (p *Parser) synchronize() {
+	p.panicking = false
+
+	// the error was found on the } closing the enclosing block, leave it
+	// for parseBlockStatement
+	if p.curTokenIs(token.RBRACE) && p.blockDepth > 0 {
+		return
+	}
+
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		endOfStatement := p.curTokenIs(token.SEMICOLON) && depth == 0
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth += 1
+		case token.RBRACE:
+			if depth > 0 {
+				depth -= 1
+			}
+		}
+		p.nextToken()
+
+		if depth > 0 {
+			continue
+		}
+		if endOfStatement {
+			return
+		}
+		switch p.curToken.Type {
+		case token.LET, token.RETURN, token.WHILE, token.FOR, token.BREAK, token.CONTINUE:
+			return
+		case token.RBRACE:
+			if p.blockDepth > 0 {
+				return
+			}
+		}
+	}
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
 	default:
 		return p.parserExpressionStatement()
 	}
@@ -193,6 +296,103 @@ func (p *Parser) parseLetStatement() ast.Statement {
 	return stmt
 }
 
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	// the condition is not part of the loop, a break in it belongs to the
+	// loop around the while
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.loopDepth += 1
+	stmt.Body = p.parseBlockStatement()
+	p.loopDepth -= 1
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() ast.Statement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.loopDepth += 1
+	stmt.Body = p.parseBlockStatement()
+	p.loopDepth -= 1
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseBreakStatement() ast.Statement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+	p.checkInsideLoop()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseContinueStatement() ast.Statement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+	p.checkInsideLoop()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// checkInsideLoop reports a break or continue that has no loop to leave.
+// The statement itself is well formed, so the parser does not have to
+// recover from it.
+func (p *Parser) checkInsideLoop() {
+	if p.loopDepth > 0 || p.panicking {
+		return
+	}
+
+	p.errors = append(p.errors, Diagnostic{
+		Code:    ErrOutsideLoop,
+		Pos:     p.curToken.Pos,
+		End:     p.curToken.End,
+		Message: fmt.Sprintf("%s outside of a loop", p.curToken.Literal),
+		Actual:  p.curToken,
+	})
+}
+
 func (p *Parser) expectPeek(t token.TokenType) bool {
 	if p.peekTokenIs(t) {
 		p.nextToken()
@@ -211,13 +411,68 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 	return p.peekToken.Type == t
 }
 
-func (p *Parser) Errors() []string {
+// Errors returns the diagnostics in the order they were found, use
+// Messages to turn them into plain strings.
+func (p *Parser) Errors() []Diagnostic {
 	return p.errors
 }
 
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf(" expected %s, got %s", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+func (p *Parser) peekError(expected ...token.TokenType) {
+	// the lexer already reported why the token is illegal
+	if p.peekTokenIs(token.ILLEGAL) {
+		p.panicking = true
+		return
+	}
+
+	p.unexpectedToken(p.peekToken, expected...)
+}
+
+// unexpectedToken reports that tok was found where one of expected was
+// required
+func (p *Parser) unexpectedToken(tok token.Token, expected ...token.TokenType) {
+	p.addError(Diagnostic{
+		Code:     ErrUnexpectedToken,
+		Pos:      tok.Pos,
+		End:      tok.End,
+		Message:  fmt.Sprintf("expected %s, got %s", expectedList(expected), tok.Type),
+		Expected: expected,
+		Actual:   tok,
+	})
+}
+
+func expectedList(expected []token.TokenType) string {
+	var out bytes.Buffer
+	for i, t := range expected {
+		if i > 0 && i == len(expected)-1 {
+			out.WriteString(" or ")
+		} else if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(string(t))
+	}
+	return out.String()
+}
+
+// addError records d unless the parser is already recovering from an
+// earlier error in the same statement, follow-up errors are almost always
+// caused by the first one
+func (p *Parser) addError(d Diagnostic) {
+	if p.panicking {
+		return
+	}
+	p.panicking = true
+	p.errors = append(p.errors, d)
+}
+
+// lexerError records the errors for malformed tokens, those are handed to
+// the parser as ILLEGAL tokens afterwards
+func (p *Parser) lexerError(pos token.Position, msg string) {
+	p.errors = append(p.errors, Diagnostic{
+		Code:    ErrIllegalToken,
+		Pos:     pos,
+		End:     pos,
+		Message: msg,
+	})
 }
 
 func (p *Parser) registerPrefix(t token.TokenType, fn prefixParseFn) {
@@ -240,10 +495,45 @@ type (
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
-	val, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	val, err := parseInt(p.curToken.Literal)
+	if err != nil {
+		p.numberError(err, "integer")
+		return nil
+	}
+
+	lit.Value = val
+	return lit
+}
+
+// parseInt converts the literal of an INT token, the lexer has already
+// made sure that the digits match the base prefix
+func parseInt(literal string) (int64, error) {
+	literal = strings.ReplaceAll(literal, "_", "")
+
+	base := 10
+	if len(literal) > 2 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 10 {
+			literal = literal[2:]
+		}
+	}
+
+	return strconv.ParseInt(literal, base, 64)
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	val, err := strconv.ParseFloat(strings.ReplaceAll(p.curToken.Literal, "_", ""), 64)
 	if err != nil {
-		msg := fmt.Sprintf(" couldnt parse %q as interger ", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.numberError(err, "float")
 		return nil
 	}
 
@@ -251,9 +541,37 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+func (p *Parser) numberError(err error, kind string) {
+	d := Diagnostic{Pos: p.curToken.Pos, End: p.curToken.End, Actual: p.curToken}
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		d.Code = ErrNumberOutOfRange
+		d.Message = fmt.Sprintf("%s literal %s is out of range", kind, p.curToken.Literal)
+	} else {
+		d.Code = ErrInvalidNumber
+		d.Message = fmt.Sprintf("could not parse %q as %s", p.curToken.Literal, kind)
+	}
+	p.addError(d)
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+// the lexer has already reported why the token is illegal, so there is
+// nothing to add here besides skipping the statement
+func (p *Parser) parseIllegal() ast.Expression {
+	p.panicking = true
+	return nil
+}
+
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf(" no prefix parse func for %s ", t)
-	p.errors = append(p.errors, msg)
+	p.addError(Diagnostic{
+		Code:    ErrNoPrefixParseFn,
+		Pos:     p.curToken.Pos,
+		End:     p.curToken.End,
+		Message: fmt.Sprintf("no prefix parse func for %s", t),
+		Actual:  p.curToken,
+	})
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
@@ -269,6 +587,43 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// assignments are right associative, the value is parsed with a lower
+// precedence than ASSIGN so that a = b = c assigns b = c first
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Target:   target,
+		Operator: p.curToken.Literal,
+	}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		p.invalidAssignmentError(target)
+		return nil
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(ASSIGN - 1)
+	return expression
+}
+
+func (p *Parser) invalidAssignmentError(target ast.Expression) {
+	if target == nil {
+		// the target itself failed to parse and was reported already
+		p.panicking = true
+		return
+	}
+
+	p.addError(Diagnostic{
+		Code:    ErrInvalidAssignment,
+		Pos:     target.Pos(),
+		End:     target.End(),
+		Message: fmt.Sprintf("cannot assign to %s", target.String()),
+		Actual:  p.curToken,
+	})
+}
+
 /*
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
@@ -312,43 +667,79 @@ func (p *Parser) parseBoolean() ast.Expression {
 func (p *Parser) parseIfExpression() ast.Expression {
 	exp := &ast.IfExpression{Token: p.curToken}
 
-	if !p.expectPeek(token.LPAREN) {
-		return nil
-	}
-
-	p.nextToken()
-	exp.Condition = p.parseExpression(LOWEST)
-
-	if !p.expectPeek(token.RPAREN) {
+	exp.Condition, exp.Consequence = p.parseCondition()
+	if exp.Consequence == nil {
 		return nil
 	}
 
-	if !p.expectPeek(token.LBRACE) {
-		return nil
-	}
+	for p.peekTokenIs(token.ELSE) {
+		p.nextToken()
 
-	exp.Consequence = p.parseBlockStatement()
+		if p.peekTokenIs(token.IF) {
+			elseIf := &ast.ElseIf{Token: p.curToken}
+			p.nextToken()
 
-	if p.peekTokenIs(token.ELSE) {
-		p.nextToken()
+			elseIf.Condition, elseIf.Consequence = p.parseCondition()
+			if elseIf.Consequence == nil {
+				return nil
+			}
+			exp.ElseIfs = append(exp.ElseIfs, elseIf)
+			continue
+		}
 
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
 
 		exp.Alternative = p.parseBlockStatement()
+		break
 	}
 	return exp
 }
 
+// parseCondition parses the (condition) { consequence } that follows an
+// if, the block is nil if either part is missing
+func (p *Parser) parseCondition() (ast.Expression, *ast.BlockStatement) {
+	if !p.expectPeek(token.LPAREN) {
+		return nil, nil
+	}
+
+	p.nextToken()
+	condition := p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil, nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil, nil
+	}
+
+	return condition, p.parseBlockStatement()
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	// recovering inside the block must not forget that the statement
+	// around it already had an error
+	outerPanicking := p.panicking
+	p.panicking = false
+	p.blockDepth += 1
+	defer func() {
+		p.blockDepth -= 1
+		p.panicking = p.panicking || outerPanicking
+	}()
+
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
 		stmt := p.parseStatement()
+		if p.panicking {
+			p.synchronize()
+			continue
+		}
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
@@ -356,6 +747,11 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		p.nextToken()
 	}
 
+	if !p.curTokenIs(token.RBRACE) {
+		p.unexpectedToken(p.curToken, token.RBRACE)
+	}
+	block.Rbrace = p.curToken
+
 	return block
 }
 
@@ -367,12 +763,18 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	}
 
 	lit.Parameters = p.parseFunctionParameters()
+	if lit.Parameters == nil {
+		return nil
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
+	outerLoopDepth := p.loopDepth
+	p.loopDepth = 0
 	lit.Body = p.parseBlockStatement()
+	p.loopDepth = outerLoopDepth
 
 	return lit
 }
@@ -385,14 +787,18 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		return identifiers
 	}
 
-	p.nextToken()
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
 
 	identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	identifiers = append(identifiers, identifier)
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
-		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
 		identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 		identifiers = append(identifiers, identifier)
 	}
@@ -404,27 +810,89 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return identifiers
 }
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
-	if p.peekTokenIs(token.RPAREN) {
+// parseExpressionList parses comma separated expressions up to the end
+// token, it returns nil if the list is not terminated
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+	if p.peekTokenIs(end) {
 		p.nextToken()
-		return args
+		return list
 	}
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	list = append(list, p.parseExpression(LOWEST))
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(end) {
 		return nil
 	}
-	return args
+	return list
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseCallArguments()
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments != nil {
+		exp.Rparen = p.curToken
+	}
 	return exp
 }
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return nil
+	}
+	array.Rbracket = p.curToken
+	return array
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	exp.Rbracket = p.curToken
+
+	return exp
+}
+
+// blocks only appear after if and fn, which parse them directly, so a {
+// in expression position always starts a hash literal
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken, Pairs: []ast.HashPair{}}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.peekTokenIs(token.RBRACE) {
+			p.peekError(token.COMMA, token.RBRACE)
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	hash.Rbrace = p.curToken
+
+	return hash
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"monkey/ast"
 	"monkey/lexer"
+	"monkey/token"
+	"reflect"
 	"testing"
 )
 
@@ -87,7 +89,7 @@ func checkParseErrors(t *testing.T, p *Parser) {
 
 	t.Errorf(" parser has %d errors ", len(errors))
 	for idx, msg := range errors {
-		t.Errorf(" Error [%d] is %q ", idx, msg)
+		t.Errorf(" Error [%d] is %q ", idx, msg.String())
 	}
 
 	t.FailNow()
@@ -145,7 +147,7 @@ func TestIdentiferExpression(t *testing.T) {
 	}
 
 	if ident.TokenLiteral() != "foobar" {
-		t.Errorf(" tokenliteral %s not equal %s ", ident.TokenLiteral, "foobar")
+		t.Errorf(" tokenliteral %s not equal %s ", ident.TokenLiteral(), "foobar")
 	}
 }
 
@@ -255,6 +257,11 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"true && false;", true, "&&", false},
+		{"true || false;", true, "||", false},
 		{"true == true;", true, "==", true},
 		{"false == false;", false, "==", false},
 		{"true != false", true, "!=", false},
@@ -330,7 +337,7 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 		},
 		{
 			"3 + 4; -5 * 5",
-			"(3 + 4)((-5) * 5)",
+			"(3 + 4);((-5) * 5)",
 		},
 		{
 			"5 > 4 == 3 < 4",
@@ -392,6 +399,66 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a + b + c * d / f + g)",
 			"add((((a + b) + ((c * d) / f)) + g))",
 		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a == b && c != d || !e",
+			"(((a == b) && (c != d)) || (!e))",
+		},
+		{
+			"x < 1 + 2 && y >= 3 * 4",
+			"((x < (1 + 2)) && (y >= (3 * 4)))",
+		},
+		{
+			"a * [1, 2, 3, 4][b * c] * d",
+			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
+		},
+		{
+			"add(a * b[2], b[1], 2 * [1, 2][1])",
+			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
+		},
+		{
+			"-a[0]",
+			"(-(a[0]))",
+		},
+		{
+			"f(x)[0](y)",
+			"(f(x)[0])(y)",
+		},
+		{
+			"a = b = c",
+			"(a = (b = c))",
+		},
+		{
+			"a += b * c",
+			"(a += (b * c))",
+		},
+		{
+			"a[i + 1] = x || y",
+			"((a[(i + 1)]) = (x || y))",
+		},
+		{
+			"x -= y %= 2",
+			"(x -= (y %= 2))",
+		},
+		{
+			"add(x = 1, y /= 2)",
+			"add((x = 1), (y /= 2))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -514,7 +581,7 @@ func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
 	}
 
 	if bo.TokenLiteral() != fmt.Sprintf("%t", value) {
-		t.Errorf(" literal is %t and not %s", bo.TokenLiteral(), fmt.Sprintf("%t", value))
+		t.Errorf(" literal is %s and not %s", bo.TokenLiteral(), fmt.Sprintf("%t", value))
 		return false
 	}
 
@@ -627,6 +694,77 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestIfElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else if (z) { z } else { 0 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(" statement does not contain 1 statements ")
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(" statement cannot be read as an expressionstatement ")
+	}
+
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf(" expression is not an if expression ")
+	}
+
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(exp.ElseIfs) != 2 {
+		t.Fatalf(" expected 2 else if branches got %d ", len(exp.ElseIfs))
+	}
+
+	testInfixExpression(t, exp.ElseIfs[0].Condition, "x", ">", "y")
+	testIdentifier(t, exp.ElseIfs[1].Condition, "z")
+
+	for i, expected := range []string{"y", "z"} {
+		consequence := exp.ElseIfs[i].Consequence
+		if len(consequence.Statements) != 1 {
+			t.Fatalf(" else if %d consequence is not 1 statement ", i)
+		}
+		testIdentifier(t, consequence.Statements[0].(*ast.ExpressionStatement).Expression, expected)
+	}
+
+	if exp.Alternative == nil {
+		t.Fatalf(" alternative is nil ")
+	}
+	testIntegerLiteral(t, exp.Alternative.Statements[0].(*ast.ExpressionStatement).Expression, 0)
+}
+
+func TestIfExpressionString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"if (a) { b }", "if (a) { b }"},
+		{"if (a) { b } else { c }", "if (a) { b } else { c }"},
+		{"if (a) { b } else if (c) { d }", "if (a) { b } else if (c) { d }"},
+		{"if (a) { b } else if (c) { d } else { e }", "if (a) { b } else if (c) { d } else { e }"},
+		{"if (a < b) { c; d } else {}", "if ((a < b)) { c;d } else {}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf(" %q rendered as %q expected %q ", tt.input, program.String(), tt.expected)
+		}
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -732,3 +870,710 @@ func TestCallExpression(t *testing.T) {
 	testInfixExpression(t, call.Arguments[1], 2, "*", 3)
 	testInfixExpression(t, call.Arguments[2], 3, "-", 4)
 }
+
+func TestParsingArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(" not able to read it as expression statement actual %T", program.Statements[0])
+	}
+
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf(" expression is not an array literal got %T ", stmt.Expression)
+	}
+
+	if len(array.Elements) != 3 {
+		t.Fatalf(" array length not equal %d != %d ", len(array.Elements), 3)
+	}
+
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestParsingEmptyArrayLiterals(t *testing.T) {
+	input := "[]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(" not able to read it as expression statement actual %T", program.Statements[0])
+	}
+
+	array, ok := stmt.Expression.(*ast.ArrayLiteral)
+	if !ok {
+		t.Fatalf(" expression is not an array literal got %T ", stmt.Expression)
+	}
+
+	if len(array.Elements) != 0 {
+		t.Errorf(" array length not equal %d != %d ", len(array.Elements), 0)
+	}
+}
+
+func TestParsingIndexExpressions(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf(" not able to read it as expression statement actual %T", program.Statements[0])
+	}
+
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf(" expression is not an index expression got %T ", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	testInfixExpression(t, indexExp.Index, 1, "+", 1)
+}
+
+func TestParsingHashLiteralsStringKeys(t *testing.T) {
+	input := `{"one": 1, "two": 2, "three": 3}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf(" expression is not a hash literal got %T ", stmt.Expression)
+	}
+
+	expected := []struct {
+		key   string
+		value int64
+	}{
+		{"one", 1},
+		{"two", 2},
+		{"three", 3},
+	}
+
+	if len(hash.Pairs) != len(expected) {
+		t.Fatalf(" hash has wrong number of pairs got %d ", len(hash.Pairs))
+	}
+
+	for i, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
+		if !ok {
+			t.Errorf(" key is not a string literal got %T ", pair.Key)
+			continue
+		}
+		if literal.Value != expected[i].key {
+			t.Errorf(" pairs[%d] key expected %q got %q ", i, expected[i].key, literal.Value)
+		}
+		testIntegerLiteral(t, pair.Value, expected[i].value)
+	}
+}
+
+func TestParsingEmptyHashLiteral(t *testing.T) {
+	input := "{}"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf(" expression is not a hash literal got %T ", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 0 {
+		t.Errorf(" hash has wrong number of pairs got %d ", len(hash.Pairs))
+	}
+}
+
+func TestParsingHashLiteralsWithExpressions(t *testing.T) {
+	input := `{"one": 0 + 1, true: 10 - 8, 3: 15 / 5}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf(" expression is not a hash literal got %T ", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 3 {
+		t.Fatalf(" hash has wrong number of pairs got %d ", len(hash.Pairs))
+	}
+
+	testInfixExpression(t, hash.Pairs[0].Value, 0, "+", 1)
+	testBooleanLiteral(t, hash.Pairs[1].Key, true)
+	testInfixExpression(t, hash.Pairs[1].Value, 10, "-", 8)
+	testIntegerLiteral(t, hash.Pairs[2].Key, 3)
+	testInfixExpression(t, hash.Pairs[2].Value, 15, "/", 5)
+}
+
+func TestHashLiteralIsNotABlock(t *testing.T) {
+	input := `if (x) { {"a": 1} } else { {} }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf(" expression is not an if expression got %T ", stmt.Expression)
+	}
+
+	for _, block := range []*ast.BlockStatement{exp.Consequence, exp.Alternative} {
+		if len(block.Statements) != 1 {
+			t.Fatalf(" block has %d statements, expected 1 ", len(block.Statements))
+		}
+		inner := block.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := inner.Expression.(*ast.HashLiteral); !ok {
+			t.Errorf(" block statement is not a hash literal got %T ", inner.Expression)
+		}
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { x; break; continue; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(" program does not contain 1 statement got %d ", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf(" statement is not a while statement got %T ", program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 3 {
+		t.Fatalf(" body does not contain 3 statements got %d ", len(stmt.Body.Statements))
+	}
+
+	if _, ok := stmt.Body.Statements[1].(*ast.BreakStatement); !ok {
+		t.Errorf(" statement 1 is not a break statement got %T ", stmt.Body.Statements[1])
+	}
+
+	if _, ok := stmt.Body.Statements[2].(*ast.ContinueStatement); !ok {
+		t.Errorf(" statement 2 is not a continue statement got %T ", stmt.Body.Statements[2])
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	input := `for x in [1, 2] { x; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf(" program does not contain 1 statement got %d ", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf(" statement is not a for statement got %T ", program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Variable, "x") {
+		return
+	}
+
+	if _, ok := stmt.Iterable.(*ast.ArrayLiteral); !ok {
+		t.Errorf(" iterable is not an array literal got %T ", stmt.Iterable)
+	}
+
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf(" body does not contain 1 statement got %d ", len(stmt.Body.Statements))
+	}
+}
+
+func TestBreakOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+	}{
+		{"while (x) { if (y) { break; } continue; }", []string{}},
+		{"for x in y { while (x) { break; } break; }", []string{}},
+		{"break;", []string{"1:1: break outside of a loop"}},
+		{"let x = 1;\ncontinue", []string{"2:1: continue outside of a loop"}},
+		{"while (x) { fn() { break; } }", []string{"1:20: break outside of a loop"}},
+		{"fn() { while (x) { continue; } break; }", []string{"1:32: break outside of a loop"}},
+		{"while (if (x) { break } else { true }) { }", []string{"1:17: break outside of a loop"}},
+		{"while (x) { while (if (y) { continue } else { true }) { } }", []string{}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := Messages(p.Errors())
+		if !reflect.DeepEqual(errors, tt.expectedErrors) {
+			t.Errorf(" input %q expected errors %q got %q ", tt.input, tt.expectedErrors, errors)
+		}
+	}
+}
+
+func TestBreakOutsideLoopKeepsParsing(t *testing.T) {
+	l := lexer.New("break; let x = 1; x;")
+	p := New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 1 {
+		t.Fatalf(" expected 1 error got %q ", Messages(p.Errors()))
+	}
+
+	if p.Errors()[0].Code != ErrOutsideLoop {
+		t.Errorf(" wrong code %s ", p.Errors()[0].Code)
+	}
+
+	if len(program.Statements) != 3 {
+		t.Errorf(" program does not contain 3 statements got %d ", len(program.Statements))
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		target   string
+		operator string
+		value    interface{}
+	}{
+		{"x = 5;", "x", "=", 5},
+		{"y += true;", "y", "+=", true},
+		{"foobar %= y;", "foobar", "%=", "y"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf(" not able to read it as expression statement actual %T", program.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.AssignExpression)
+		if !ok {
+			t.Fatalf(" expression is not an assign expression got %T ", stmt.Expression)
+		}
+
+		if !testIdentifier(t, exp.Target, tt.target) {
+			return
+		}
+
+		if exp.Operator != tt.operator {
+			t.Errorf(" operator expected %s got %s ", tt.operator, exp.Operator)
+		}
+
+		testLiteralExpression(t, exp.Value, tt.value)
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let = 5;", "1:5: expected IDENT, got ="},
+		{"let x 5;", "1:7: expected =, got INT"},
+		{"let x = 5;\nlet y = ;", "2:9: no prefix parse func for ;"},
+		{"add(1,\n  2", "2:4: expected ), got EOF"},
+		{"[1, 2", "1:6: expected ], got EOF"},
+		{"a[1;", "1:4: expected ], got ;"},
+		{`{"a" 1}`, "1:6: expected :, got INT"},
+		{`{"a": 1 "b": 2}`, "1:9: expected , or }, got STRING"},
+		{"for 1 in x {}", "1:5: expected IDENT, got INT"},
+		{"for x of y {}", "1:7: expected IN, got IDENT"},
+		{"while x {}", "1:7: expected (, got IDENT"},
+		{"1 = 2", "1:1: cannot assign to 1"},
+		{"let y = 1;\na + b *= c", "2:1: cannot assign to (a + b)"},
+		{"f() = 1", "1:1: cannot assign to f()"},
+		{"99999999999999999999", "1:1: integer literal 99999999999999999999 is out of range"},
+		{"let x = 1;\nx + 0x1_0000_0000_0000_0000", "2:5: integer literal 0x1_0000_0000_0000_0000 is out of range"},
+		{"1e999", "1:1: float literal 1e999 is out of range"},
+		{"fn(1) {}", "1:4: expected IDENT, got INT"},
+		{"fn(x, if) { x }", "1:7: expected IDENT, got IF"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf(" input %q produced no errors ", tt.input)
+			continue
+		}
+
+		if errors[0].String() != tt.expectedError {
+			t.Errorf(" input %q expected error %q got %q ", tt.input, tt.expectedError, errors[0].String())
+		}
+	}
+}
+
+func TestNodeSpans(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"foobar;", "foobar"},
+		{"  12345 ;", "12345"},
+		{"-a * b", "-a * b"},
+		{"a + add(b * c)", "a + add(b * c)"},
+		{"add(1,\n 2)", "add(1,\n 2)"},
+		{"if (x < y) { x } else { y; }", "if (x < y) { x } else { y; }"},
+		{"fn(x, y) {\n  x + y;\n}", "fn(x, y) {\n  x + y;\n}"},
+		{"let x = 5 * 5;", "let x = 5 * 5"},
+		{"return true;", "return true"},
+		{"[1, 2 * 3] ;", "[1, 2 * 3]"},
+		{"a[i + 1];", "a[i + 1]"},
+		{"if (a) { b } else if (c) { d };", "if (a) { b } else if (c) { d }"},
+		{"while (a) { b; }", "while (a) { b; }"},
+		{"for x in xs { break; }", "for x in xs { break; }"},
+		{`{"a": 1, 2: b} ;`, `{"a": 1, 2: b}`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf(" program does not contain 1 statement got %d ", len(program.Statements))
+		}
+
+		stmt := program.Statements[0]
+		actual := tt.input[stmt.Pos().Offset:stmt.End().Offset]
+		if actual != tt.expected {
+			t.Errorf(" span of %q is %q (%s-%s), expected %q ", tt.input, actual, stmt.Pos(), stmt.End(), tt.expected)
+		}
+	}
+}
+
+func TestNestedNodeSpans(t *testing.T) {
+	input := "let f = fn(a) {\n\treturn a(1, 2);\n};"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	let := program.Statements[0].(*ast.LetStatement)
+	function := let.Value.(*ast.FunctionLiteral)
+	ret := function.Body.Statements[0].(*ast.ReturnStatement)
+	call := ret.ReturnValue.(*ast.CallExpression)
+
+	tests := []struct {
+		node          ast.Node
+		expectedStart string
+		expectedEnd   string
+	}{
+		{let.Name, "1:5", "1:6"},
+		{function, "1:9", "3:2"},
+		{function.Body, "1:15", "3:2"},
+		{ret, "2:2", "2:16"},
+		{call, "2:9", "2:16"},
+		{call.Arguments[1], "2:14", "2:15"},
+	}
+
+	for i, tt := range tests {
+		if tt.node.Pos().String() != tt.expectedStart {
+			t.Errorf("tests[%d] - %T starts at %s, expected %s", i, tt.node, tt.node.Pos(), tt.expectedStart)
+		}
+		if tt.node.End().String() != tt.expectedEnd {
+			t.Errorf("tests[%d] - %T ends at %s, expected %s", i, tt.node, tt.node.End(), tt.expectedEnd)
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello \"world\"\n";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf(" exp not *ast.StringLiteral got %T ", stmt.Expression)
+	}
+
+	if literal.Value != "hello \"world\"\n" {
+		t.Errorf(" literal.Value not %q got %q ", "hello \"world\"\n", literal.Value)
+	}
+
+	if literal.String() != `"hello \"world\"\n"` {
+		t.Errorf(" literal.String() does not quote the value got %s ", literal.String())
+	}
+}
+
+func TestLexerErrorsAreReported(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+	}{
+		{"let s = \"abc;\nlet t = 1;", []string{"1:9: unterminated string literal"}},
+		{`let s = "a\qb";`, []string{`1:11: invalid escape sequence \q`}},
+		{"let x = 1 @ 2;", []string{`1:11: illegal character "@"`}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := Messages(p.Errors())
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf(" input %q expected errors %q got %q ", tt.input, tt.expectedErrors, errors)
+			continue
+		}
+
+		for i, msg := range tt.expectedErrors {
+			if errors[i] != msg {
+				t.Errorf(" input %q expected error %q got %q ", tt.input, msg, errors[i])
+			}
+		}
+	}
+}
+
+func TestNumberLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"0", int64(0)},
+		{"1_000_000", int64(1000000)},
+		{"0x1F", int64(31)},
+		{"0XfF", int64(255)},
+		{"0x_dead_beef", int64(0xdeadbeef)},
+		{"0o17", int64(15)},
+		{"0b1010", int64(10)},
+		{"010", int64(10)},
+		{"9223372036854775807", int64(9223372036854775807)},
+		{"1.5", 1.5},
+		{"0.25", 0.25},
+		{"1e3", 1000.0},
+		{"1.5e-3", 0.0015},
+		{"2E+2", 200.0},
+		{"1_000.000_1", 1000.0001},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		switch expected := tt.expected.(type) {
+		case int64:
+			lit, ok := stmt.Expression.(*ast.IntegerLiteral)
+			if !ok {
+				t.Errorf(" %q is not *ast.IntegerLiteral got %T ", tt.input, stmt.Expression)
+				continue
+			}
+			if lit.Value != expected {
+				t.Errorf(" %q parsed as %d, expected %d ", tt.input, lit.Value, expected)
+			}
+		case float64:
+			lit, ok := stmt.Expression.(*ast.FloatLiteral)
+			if !ok {
+				t.Errorf(" %q is not *ast.FloatLiteral got %T ", tt.input, stmt.Expression)
+				continue
+			}
+			if lit.Value != expected {
+				t.Errorf(" %q parsed as %g, expected %g ", tt.input, lit.Value, expected)
+			}
+		}
+
+		if stmt.Expression.String() != tt.input {
+			t.Errorf(" String() of %q gives %q ", tt.input, stmt.Expression.String())
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedErrors     []string
+		expectedStatements []string
+	}{
+		{
+			"let = 5;\nlet y = 10;\nlet 3;\nlet z = y;",
+			[]string{"1:5: expected IDENT, got =", "3:5: expected IDENT, got INT"},
+			[]string{"let y = 10;", "let z = y;"},
+		},
+		{
+			"add(1, 2\nlet y = 1;",
+			[]string{"2:1: expected ), got LET"},
+			[]string{"let y = 1;"},
+		},
+		{
+			"fn(x { x }\nlet y = 2;",
+			[]string{"1:6: expected ), got {"},
+			[]string{"let y = 2;"},
+		},
+		{
+			"let f = fn(x) {\n  let = 1;\n  x\n};\nf(1);",
+			[]string{"2:7: expected IDENT, got ="},
+			[]string{"let f = fn(x) { x };", "f(1)"},
+		},
+		{
+			"if (x > ) { 1 } else { 2 }; 3",
+			[]string{"1:9: no prefix parse func for )"},
+			[]string{"3"},
+		},
+		{
+			"let a = (1 + 2;\nlet b = (3 * ) + 4;\nlet c = 5;",
+			[]string{"1:15: expected ), got ;", "2:14: no prefix parse func for )"},
+			[]string{"let c = 5;"},
+		},
+		{
+			"if (a) { let x = } b",
+			[]string{"1:18: no prefix parse func for }"},
+			[]string{"if (a) {}", "b"},
+		},
+		{
+			"x; @; y",
+			[]string{`1:4: illegal character "@"`},
+			[]string{"x", "y"},
+		},
+		{
+			"let s = \"abc\\q\"; let t = @;\nreturn s;",
+			[]string{`1:13: invalid escape sequence \q`, `1:26: illegal character "@"`},
+			[]string{`let s = "abc\\q";`, "return s;"},
+		},
+		{
+			"let f = fn(x) {\n  x + 1;\n",
+			[]string{"3:1: expected }, got EOF"},
+			[]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := Messages(p.Errors())
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf(" input %q expected errors %q got %q ", tt.input, tt.expectedErrors, errors)
+		} else {
+			for i, msg := range tt.expectedErrors {
+				if errors[i] != msg {
+					t.Errorf(" input %q expected error %q got %q ", tt.input, msg, errors[i])
+				}
+			}
+		}
+
+		statements := []string{}
+		for _, stmt := range program.Statements {
+			statements = append(statements, stmt.String())
+		}
+		if fmt.Sprintf("%q", statements) != fmt.Sprintf("%q", tt.expectedStatements) {
+			t.Errorf(" input %q expected statements %q got %q ", tt.input, tt.expectedStatements, statements)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedCode     Code
+		expectedPos      string
+		expectedEnd      string
+		expectedExpected []token.TokenType
+		expectedActual   token.TokenType
+	}{
+		{"let = 5;", ErrUnexpectedToken, "1:5", "1:6", []token.TokenType{token.IDENT}, token.ASSIGN},
+		{"add(1,\n  2", ErrUnexpectedToken, "2:4", "2:4", []token.TokenType{token.RPAREN}, token.EOF},
+		{"fn(x) { x", ErrUnexpectedToken, "1:10", "1:10", []token.TokenType{token.RBRACE}, token.EOF},
+		{"let x = * 2;", ErrNoPrefixParseFn, "1:9", "1:10", nil, token.ASTERISK},
+		{"  99999999999999999999", ErrNumberOutOfRange, "1:3", "1:23", nil, token.INT},
+		{"\"abc", ErrIllegalToken, "1:1", "1:1", nil, ""},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) != 1 {
+			t.Errorf(" input %q expected 1 diagnostic got %q ", tt.input, Messages(p.Errors()))
+			continue
+		}
+
+		d := p.Errors()[0]
+		if d.Severity != SeverityError {
+			t.Errorf(" input %q severity is %s ", tt.input, d.Severity)
+		}
+		if d.Code != tt.expectedCode {
+			t.Errorf(" input %q code is %s expected %s ", tt.input, d.Code, tt.expectedCode)
+		}
+		if d.Pos.String() != tt.expectedPos || d.End.String() != tt.expectedEnd {
+			t.Errorf(" input %q span is %s-%s expected %s-%s ", tt.input, d.Pos, d.End, tt.expectedPos, tt.expectedEnd)
+		}
+		if fmt.Sprint(d.Expected) != fmt.Sprint(tt.expectedExpected) {
+			t.Errorf(" input %q expected set is %v expected %v ", tt.input, d.Expected, tt.expectedExpected)
+		}
+		if d.Actual.Type != tt.expectedActual {
+			t.Errorf(" input %q actual token is %q expected %q ", tt.input, d.Actual.Type, tt.expectedActual)
+		}
+	}
+}
+
+func TestMessages(t *testing.T) {
+	diagnostics := []Diagnostic{
+		{Pos: token.Position{Filename: "a.mk", Line: 3, Column: 7}, Message: "expected ), got EOF"},
+		{Pos: token.Position{Line: 1, Column: 1}, Message: "unterminated string literal"},
+	}
+
+	messages := Messages(diagnostics)
+	expected := []string{"a.mk:3:7: expected ), got EOF", "1:1: unterminated string literal"}
+	if fmt.Sprint(messages) != fmt.Sprint(expected) {
+		t.Errorf(" Messages() gave %q expected %q ", messages, expected)
+	}
+
+	if diagnostics[0].Error() != expected[0] {
+		t.Errorf(" Error() gave %q expected %q ", diagnostics[0].Error(), expected[0])
+	}
+}
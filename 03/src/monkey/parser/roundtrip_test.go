@@ -0,0 +1,500 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// generator builds random but valid programs. Every program it returns
+// parses without errors when written out with String, break and continue
+// only appear inside loops and assignments only target names and index
+// expressions.
+type generator struct {
+	rand *rand.Rand
+	// depth limits how deep expressions and blocks nest
+	depth int
+	// inLoop is set while generating a loop body outside of any function
+	// literal
+	inLoop bool
+}
+
+func newGenerator(seed int64) *generator {
+	return &generator{rand: rand.New(rand.NewSource(seed))}
+}
+
+var (
+	generatedNames     = []string{"a", "b", "foo", "bar", "x1", "_y", "héllo"}
+	generatedPrefixes  = []string{"-", "!"}
+	generatedOperators = []string{"+", "-", "*", "/", "%", "<", ">", "<=", ">=", "==", "!=", "&&", "||"}
+	generatedAssigns   = []string{"=", "+=", "-=", "*=", "/=", "%="}
+	generatedStrings   = []string{"", "hello", "a \"quoted\" word", "tab\there", "line\nbreak", "back\\slash", "ünïcödé", "\x00\x7f"}
+)
+
+func (g *generator) program() *ast.Program {
+	program := &ast.Program{}
+	for i := g.rand.Intn(4) + 1; i > 0; i-- {
+		program.Statements = append(program.Statements, g.statement())
+	}
+	return program
+}
+
+func (g *generator) pick(choices []string) string {
+	return choices[g.rand.Intn(len(choices))]
+}
+
+func (g *generator) ident() *ast.Identifier {
+	name := g.pick(generatedNames)
+	return &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+
+func (g *generator) statement() ast.Statement {
+	g.depth += 1
+	defer func() { g.depth -= 1 }()
+
+	// loops only below the depth limit, break and continue come last
+	loops := g.depth < 3
+	kinds := 3
+	if loops {
+		kinds = 5
+	}
+	if g.inLoop {
+		kinds += 2
+	}
+
+	switch n := g.rand.Intn(kinds); {
+	case n == 0:
+		return &ast.LetStatement{Token: token.Token{Type: token.LET, Literal: "let"}, Name: g.ident(), Value: g.expression()}
+	case n == 1:
+		return &ast.ReturnStatement{Token: token.Token{Type: token.RETURN, Literal: "return"}, ReturnValue: g.expression()}
+	case n == 2:
+		return &ast.ExpressionStatement{Expression: g.expression()}
+	case n == 3 && loops:
+		return &ast.WhileStatement{Token: token.Token{Type: token.WHILE, Literal: "while"}, Condition: g.expression(), Body: g.loopBody()}
+	case n == 4 && loops:
+		return &ast.ForStatement{Token: token.Token{Type: token.FOR, Literal: "for"}, Variable: g.ident(), Iterable: g.expression(), Body: g.loopBody()}
+	case n == kinds-2:
+		return &ast.BreakStatement{Token: token.Token{Type: token.BREAK, Literal: "break"}}
+	default:
+		return &ast.ContinueStatement{Token: token.Token{Type: token.CONTINUE, Literal: "continue"}}
+	}
+}
+
+func (g *generator) block() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: token.Token{Type: token.LBRACE, Literal: "{"}}
+	for i := g.rand.Intn(3); i > 0; i-- {
+		block.Statements = append(block.Statements, g.statement())
+	}
+	return block
+}
+
+func (g *generator) loopBody() *ast.BlockStatement {
+	inLoop := g.inLoop
+	g.inLoop = true
+	defer func() { g.inLoop = inLoop }()
+	return g.block()
+}
+
+func (g *generator) expression() ast.Expression {
+	g.depth += 1
+	defer func() { g.depth -= 1 }()
+
+	if g.depth > 5 || g.rand.Intn(3) == 0 {
+		return g.leaf()
+	}
+
+	switch g.rand.Intn(10) {
+	case 0:
+		op := g.pick(generatedPrefixes)
+		return &ast.PrefixExpression{Token: token.Token{Literal: op}, Operator: op, Right: g.expression()}
+	case 1:
+		op := g.pick(generatedOperators)
+		return &ast.InfixExpression{Token: token.Token{Literal: op}, Left: g.expression(), Operator: op, Right: g.expression()}
+	case 2:
+		exp := &ast.IfExpression{Token: token.Token{Type: token.IF, Literal: "if"}, Condition: g.expression(), Consequence: g.block()}
+		for i := g.rand.Intn(3); i > 0; i-- {
+			exp.ElseIfs = append(exp.ElseIfs, &ast.ElseIf{Condition: g.expression(), Consequence: g.block()})
+		}
+		if g.rand.Intn(2) == 0 {
+			exp.Alternative = g.block()
+		}
+		return exp
+	case 3:
+		// break inside a function literal cannot leave the loop around it
+		inLoop := g.inLoop
+		g.inLoop = false
+		defer func() { g.inLoop = inLoop }()
+
+		fn := &ast.FunctionLiteral{Token: token.Token{Type: token.FUNCTION, Literal: "fn"}, Parameters: []*ast.Identifier{}}
+		for i := g.rand.Intn(3); i > 0; i-- {
+			fn.Parameters = append(fn.Parameters, g.ident())
+		}
+		fn.Body = g.block()
+		return fn
+	case 4:
+		return &ast.CallExpression{Function: g.expression(), Arguments: g.expressions()}
+	case 5:
+		return &ast.ArrayLiteral{Elements: g.expressions()}
+	case 6:
+		return &ast.IndexExpression{Left: g.expression(), Index: g.expression()}
+	case 7:
+		hash := &ast.HashLiteral{Pairs: []ast.HashPair{}}
+		for i := g.rand.Intn(3); i > 0; i-- {
+			hash.Pairs = append(hash.Pairs, ast.HashPair{Key: g.expression(), Value: g.expression()})
+		}
+		return hash
+	default:
+		var target ast.Expression = g.ident()
+		if g.rand.Intn(2) == 0 {
+			target = &ast.IndexExpression{Left: g.expression(), Index: g.expression()}
+		}
+		op := g.pick(generatedAssigns)
+		return &ast.AssignExpression{Token: token.Token{Literal: op}, Target: target, Operator: op, Value: g.expression()}
+	}
+}
+
+func (g *generator) expressions() []ast.Expression {
+	list := []ast.Expression{}
+	for i := g.rand.Intn(3); i > 0; i-- {
+		list = append(list, g.expression())
+	}
+	return list
+}
+
+func (g *generator) leaf() ast.Expression {
+	switch g.rand.Intn(5) {
+	case 0:
+		return g.ident()
+	case 1:
+		value := g.rand.Int63n(math.MaxInt64)
+		if g.rand.Intn(2) == 0 {
+			value = g.rand.Int63n(10)
+		}
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: strconv.FormatInt(value, 10)}, Value: value}
+	case 2:
+		value := g.rand.ExpFloat64()
+		literal := strconv.FormatFloat(value, 'g', -1, 64)
+		if !strings.ContainsAny(literal, ".e") {
+			literal += ".0"
+		}
+		return &ast.FloatLiteral{Token: token.Token{Type: token.FLOAT, Literal: literal}, Value: value}
+	case 3:
+		value := g.pick(generatedStrings)
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: value}, Value: value}
+	default:
+		value := g.rand.Intn(2) == 0
+		return &ast.Boolean{Token: token.Token{Literal: strconv.FormatBool(value)}, Value: value}
+	}
+}
+
+// equalNodes compares two trees by structure and values only. Tokens are
+// skipped since positions and spellings like 0x10 and 16 do not matter.
+func equalNodes(a, b interface{}) bool {
+	return equalValues(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+var tokenType = reflect.TypeOf(token.Token{})
+
+func equalValues(a, b reflect.Value) bool {
+	if a.IsValid() != b.IsValid() {
+		return false
+	}
+	if !a.IsValid() {
+		return true
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return equalValues(a.Elem(), b.Elem())
+	case reflect.Slice:
+		// a nil list and an empty one are the same to the parser
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equalValues(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if a.Field(i).Type() == tokenType {
+				continue
+			}
+			if !equalValues(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a.Interface(), b.Interface())
+	}
+}
+
+// roundTrip writes program with String and parses it again, it describes
+// the first difference it finds
+func roundTrip(program *ast.Program) error {
+	source := program.String()
+
+	p := New(lexer.New(source))
+	parsed := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return fmt.Errorf("%q does not parse: %s", source, p.Errors()[0])
+	}
+
+	if !equalNodes(program, parsed) {
+		return fmt.Errorf("%q parses back as %q", source, parsed.String())
+	}
+
+	return nil
+}
+
+// shrink keeps replacing program with smaller variants that still fail
+// the round trip until none of them does
+func shrink(program *ast.Program) *ast.Program {
+	for steps := 0; steps < 1000; steps++ {
+		smaller := false
+		for _, candidate := range shrinkCandidates(program) {
+			if roundTrip(candidate) != nil {
+				program = candidate
+				smaller = true
+				break
+			}
+		}
+		if !smaller {
+			break
+		}
+	}
+	return program
+}
+
+// shrinkCandidates lists copies of program with one statement removed or
+// one expression replaced by one of its operands
+func shrinkCandidates(program *ast.Program) []*ast.Program {
+	candidates := []*ast.Program{}
+
+	for i := 0; ; i++ {
+		candidate := clone(program).(*ast.Program)
+		if !removeStatement(candidate, i) {
+			break
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	for i := 0; ; i++ {
+		operands := expressionOperands(program, i)
+		if operands == nil {
+			break
+		}
+		for j := range operands {
+			candidate := clone(program).(*ast.Program)
+			replaceExpression(candidate, i, clone(operands[j]).(ast.Expression))
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates
+}
+
+// removeStatement drops the n-th statement of any statement list in
+// program, it reports false when there are fewer statements
+func removeStatement(program *ast.Program, n int) bool {
+	removed := false
+	drop := func(list []ast.Statement) []ast.Statement {
+		if removed || n >= len(list) {
+			n -= len(list)
+			return list
+		}
+		removed = true
+		return append(list[:n:n], list[n+1:]...)
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Program:
+			node.Statements = drop(node.Statements)
+		case *ast.BlockStatement:
+			node.Statements = drop(node.Statements)
+		}
+		return !removed
+	})
+	return removed
+}
+
+// expressionOperands returns the direct operands of the n-th expression
+// of program that may replace it, nil when there is no n-th expression.
+// Targets of assignments are never replaced since not every expression
+// can be assigned to.
+func expressionOperands(program *ast.Program, n int) []ast.Expression {
+	var operands []ast.Expression
+	ast.Inspect(program, func(node ast.Node) bool {
+		e, ok := node.(ast.Expression)
+		if !ok || operands != nil {
+			return operands == nil
+		}
+		if n > 0 {
+			n -= 1
+			return true
+		}
+
+		operands = []ast.Expression{}
+		ast.Inspect(e, func(child ast.Node) bool {
+			if child == e {
+				return true
+			}
+			if c, ok := child.(ast.Expression); ok && !isAssignTarget(e, c) {
+				operands = append(operands, c)
+			}
+			return false
+		})
+		return false
+	})
+	return operands
+}
+
+func isAssignTarget(parent ast.Expression, child ast.Expression) bool {
+	assign, ok := parent.(*ast.AssignExpression)
+	return ok && assign.Target == child
+}
+
+// replaceExpression swaps the n-th expression of program, counted the
+// same way as in expressionOperands, for replacement
+func replaceExpression(program *ast.Program, n int, replacement ast.Expression) {
+	var target ast.Expression
+	ast.Inspect(program, func(node ast.Node) bool {
+		if e, ok := node.(ast.Expression); ok && target == nil {
+			if n == 0 {
+				target = e
+			}
+			n -= 1
+		}
+		return target == nil
+	})
+
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if node == target {
+			return replacement
+		}
+		return node
+	})
+}
+
+// clone copies a tree so that shrinking one candidate does not change
+// the others
+func clone(node ast.Node) ast.Node {
+	return cloneValue(reflect.ValueOf(node)).Interface().(ast.Node)
+}
+
+func cloneValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(cloneValue(v.Elem()))
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i)))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			c.Field(i).Set(cloneValue(v.Field(i)))
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	runs := 2000
+	if testing.Short() {
+		runs = 200
+	}
+
+	for seed := int64(0); seed < int64(runs); seed++ {
+		program := newGenerator(seed).program()
+		if err := roundTrip(program); err != nil {
+			minimal := shrink(program)
+			t.Fatalf(" seed %d fails the round trip: %s\n minimal counterexample: %s ", seed, err, roundTrip(minimal))
+		}
+	}
+}
+
+func TestRoundTripCoversEveryNode(t *testing.T) {
+	seen := map[string]bool{}
+	for seed := int64(0); seed < 200; seed++ {
+		ast.Inspect(newGenerator(seed).program(), func(node ast.Node) bool {
+			if node != nil {
+				seen[reflect.TypeOf(node).Elem().Name()] = true
+			}
+			return true
+		})
+	}
+
+	expected := []string{
+		"Program", "LetStatement", "ReturnStatement", "ExpressionStatement", "BlockStatement",
+		"WhileStatement", "ForStatement", "BreakStatement", "ContinueStatement",
+		"Identifier", "IntegerLiteral", "FloatLiteral", "StringLiteral", "Boolean",
+		"PrefixExpression", "InfixExpression", "AssignExpression", "IfExpression",
+		"FunctionLiteral", "CallExpression", "ArrayLiteral", "IndexExpression", "HashLiteral",
+	}
+	for _, name := range expected {
+		if !seen[name] {
+			t.Errorf(" the generator never produced a %s ", name)
+		}
+	}
+}
+
+func TestShrink(t *testing.T) {
+	// a program String cannot write back, the break is not in a loop
+	// once it is parsed on its own
+	bad := &ast.Program{Statements: []ast.Statement{
+		&ast.LetStatement{Name: &ast.Identifier{Value: "a"}, Value: &ast.IntegerLiteral{Token: token.Token{Literal: "1"}, Value: 1}},
+		&ast.ExpressionStatement{Expression: &ast.InfixExpression{
+			Left:     &ast.IntegerLiteral{Token: token.Token{Literal: "2"}, Value: 2},
+			Operator: "+",
+			Right: &ast.FunctionLiteral{Parameters: []*ast.Identifier{}, Body: &ast.BlockStatement{
+				Statements: []ast.Statement{&ast.BreakStatement{Token: token.Token{Literal: "break"}}},
+			}},
+		}},
+	}}
+
+	minimal := shrink(bad)
+
+	if len(minimal.Statements) != 1 {
+		t.Fatalf(" expected one statement left got %d ", len(minimal.Statements))
+	}
+	if minimal.String() != "fn() { break; }" {
+		t.Errorf(" counterexample was not minimised got %q ", minimal.String())
+	}
+}
@@ -4,12 +4,22 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"monkey/lexer"	
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
 	"monkey/parser"
+	"strings"
 )
 
 const prompt = "#> "
 
+// printers show the ast of the rest of a line that starts with their name
+// instead of evaluating it
+var printers = map[string]func(ast.Node) string{
+	":dot":   ast.DOT,
+	":sexpr": ast.SExpr,
+}
 
 const MONKEY_FACE = `
 	MONKE_FACE_HHAHA
@@ -17,34 +27,48 @@ const MONKEY_FACE = `
 
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
+	env := object.NewEnvironment()
 
 	for {
-		fmt.Printf(prompt)
+		fmt.Fprint(out, prompt)
 		scanned := scanner.Scan()
 		if !scanned {
 			return
 		}
 
 		line := scanner.Text()
+		fields := strings.SplitN(line, " ", 2)
+		printer := printers[fields[0]]
+		if printer != nil {
+			line = strings.TrimPrefix(line, fields[0])
+		}
 		l := lexer.New(line)
 		p := parser.New(l)
 		program := p.ParseProgram()
 
-		if len(p.Errors()) != 0 {	
-			printParseErrors(out, p.Errors())
+		if len(p.Errors()) != 0 {
+			printParseErrors(out, parser.Messages(p.Errors()))
 			continue
 		}
 
-		io.WriteString(out, program.String())
-		io.WriteString(out, "\n")
+		if printer != nil {
+			io.WriteString(out, printer(program))
+			continue
+		}
+
+		evaluated := evaluator.Eval(program, env)
+		if evaluated != nil {
+			io.WriteString(out, evaluated.Inspect())
+			io.WriteString(out, "\n")
+		}
 	}
 }
 
-func printParseErrors(out io.Writer, errors[] string) {
+func printParseErrors(out io.Writer, errors []string) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, " Something wrong about Monkey Programming language!!! \n")
 	io.WriteString(out, "parse errors! \n")
-	for _,msg := range errors {
+	for _, msg := range errors {
 		io.WriteString(out, "\t"+msg+"\n")
 	}
-}
\ No newline at end of file
+}
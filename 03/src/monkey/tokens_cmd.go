@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"monkey/lexer"
+	"monkey/token"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// tokensCommand implements monkey tokens [-json] [-comments] [file]. It
+// prints the tokens NextToken returns up to and including EOF, as a table
+// or as one JSON object per line. Without a file it lexes standard input.
+func tokensCommand(args []string) int {
+	flags := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print one JSON object per token")
+	comments := flags.Bool("comments", false, "include comments as COMMENT tokens")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: monkey tokens [-json] [-comments] [file]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+
+	filename, in := "<stdin>", io.Reader(os.Stdin)
+	if flags.NArg() == 1 {
+		filename = flags.Arg(0)
+		f, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkey tokens: %s\n", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	status := 0
+	l := lexer.NewFileReader(filename, in)
+	l.SetErrorHandler(func(pos token.Position, msg string) {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", pos, msg)
+		status = 1
+	})
+	if *comments {
+		l.SetMode(lexer.ScanComments)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for {
+			tok := l.NextToken()
+			enc.Encode(jsonToken{tok.Type, tok.Literal, tok.Pos.Line, tok.Pos.Column})
+			if tok.Type == token.EOF {
+				return status
+			}
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tLITERAL\tLINE\tCOLUMN")
+	for {
+		tok := l.NextToken()
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", tok.Type, strconv.Quote(tok.Literal), tok.Pos.Line, tok.Pos.Column)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+	w.Flush()
+	return status
+}
+
+type jsonToken struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+	Line    int             `json:"line"`
+	Column  int             `json:"column"`
+}